@@ -1,3 +1,3 @@
 module scm.applatform.io/mob/go-concurrency
 
-go 1.14
+go 1.21
@@ -2,12 +2,18 @@ package main
 
 import (
 	"fmt"
+	"runtime"
 	"time"
+
+	"scm.applatform.io/mob/go-concurrency/patterns/stream"
 )
 
 /**
 At time you may find yourself wanting to combine one or more `done` channels into a single done channel that closes
 if any of its components channels close.
+
+The recursive version below starts a goroutine for every two or three channels.  stream.Or does the same from a single
+goroutine, see patterns/stream/signal.go, and stream.And closes once all of them are closed.
 */
 
 func main() {
@@ -62,4 +68,34 @@ func main() {
 	)
 	fmt.Printf("done after %v\n", time.Since(start))
 
+	start = time.Now()
+	<-stream.Or(
+		sig(2*time.Hour),
+		sig(5*time.Minute),
+		sig(1*time.Second),
+		sig(1*time.Hour),
+		sig(1*time.Minute),
+	)
+	fmt.Printf("stream.Or done after %v\n", time.Since(start))
+
+	start = time.Now()
+	<-stream.And(
+		sig(1*time.Second),
+		sig(2*time.Second),
+	)
+	fmt.Printf("stream.And done after %v\n", time.Since(start))
+
+	// goroutines needed to wait for 10000 channels
+	channels := make([]<-chan interface{}, 10000)
+	for i := range channels {
+		channels[i] = make(chan interface{})
+	}
+	count := func(combine func()) int {
+		before := runtime.NumGoroutine()
+		combine()
+		time.Sleep(100 * time.Millisecond) // let the goroutines start
+		return runtime.NumGoroutine() - before
+	}
+	fmt.Printf("recursive or: %d goroutines\n", count(func() { or(channels...) }))
+	fmt.Printf("stream.Or: %d goroutines\n", count(func() { stream.Or(channels...) }))
 }
@@ -4,12 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+
+	"scm.applatform.io/mob/go-concurrency/patterns/stream"
 )
 
-type Result struct { // we create a type that could hold either a Error or a Response
-	Error    error
-	Response *http.Response
-}
+type Result = stream.Result[*http.Response] // we use a type that could hold either a Error or a Response
 
 func main() {
 	checkStatus := func(ctx context.Context, urls ...string) <-chan Result {
@@ -19,7 +18,7 @@ func main() {
 			for _, url := range urls {
 				var result Result
 				resp, err := http.Get(url)
-				result = Result{Error: err, Response: resp} // no longer swallow error but return a result
+				result = Result{Err: err, Value: resp} // no longer swallow error but return a result
 				select {
 				case <-ctx.Done():
 					return
@@ -32,10 +31,10 @@ func main() {
 
 	urls := []string{"https://www.google.com", "https://badhost"}
 	for result := range checkStatus(context.Background(), urls...) {
-		if result.Error != nil { // now we are dealing with the error
-			fmt.Printf("error: %v\n", result.Error)
+		if result.Err != nil { // now we are dealing with the error
+			fmt.Printf("error: %v\n", result.Err)
 			continue
 		}
-		fmt.Printf("Responses: %v\n", result.Response.Status)
+		fmt.Printf("Responses: %v\n", result.Value.Status)
 	}
 }
@@ -4,91 +4,40 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"strings"
+
+	"scm.applatform.io/mob/go-concurrency/patterns/stream"
 )
 
 /**
-Some handy generators
+Some handy generators.  They used to work on chan interface{}, which meant a type assertion, and a panic when the
+wrong type came along, at the end of every pipeline.  The stream package does the same with type parameters.
 */
 
-func repeat(ctx context.Context, values ...interface{}) <-chan interface{} {
-	valueStream := make(chan interface{})
-	go func() {
-		defer close(valueStream)
-		for {
-			for _, v := range values {
-				select {
-				case <-ctx.Done():
-					return
-				case valueStream <- v:
-				}
-			}
-		}
-	}()
-	return valueStream
-}
-
-func take(ctx context.Context, valueStream <-chan interface{}, num int) <-chan interface{} {
-	takeStream := make(chan interface{})
-	go func() {
-		defer close(takeStream)
-		for i := 0; i < num; i++ {
-			select {
-			case <-ctx.Done():
-				return
-			case takeStream <- <-valueStream:
-			}
-		}
-	}()
-	return takeStream
-}
-
-func repeatFn(ctx context.Context, fn func() interface{}) <-chan interface{} {
-	valueStream := make(chan interface{})
-	go func() {
-		defer close(valueStream)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case valueStream <- fn():
-			}
-		}
-	}()
-	return valueStream
-}
-
-func toString(ctx context.Context, valueStream <-chan interface{}) <-chan string {
-	stringStream := make(chan string)
-	go func() {
-		defer close(stringStream)
-		for v := range valueStream {
-			select {
-			case <-ctx.Done():
-			case stringStream <- v.(string):
-			}
-		}
-	}()
-	return stringStream
-}
-
 func main() {
-	ctx := context.Background()
-	for num := range take(ctx, repeat(ctx, 1), 10) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // stops the generators, they'd run forever otherwise
+
+	for num := range stream.Take(ctx, stream.Repeat(ctx, 1), 10) {
 		fmt.Printf("%v ", num)
 	}
 	fmt.Println()
 
-	randFn := func() interface{} { return rand.Int() }
-
-	for num := range take(ctx, repeatFn(ctx, randFn), 10) {
+	for num := range stream.Take(ctx, stream.RepeatFn(ctx, rand.Int), 10) {
 		fmt.Println(num)
 	}
 
 	var message string
-	for token := range toString(ctx, take(ctx, repeat(ctx, "I", "am."), 5)) {
+	for token := range stream.Take(ctx, stream.Repeat(ctx, "I", "am."), 5) {
 		message += token
 	}
 
 	fmt.Printf("message: %s...\n", message)
 
+	evens := stream.Filter(ctx, stream.Take(ctx, stream.RepeatFn(ctx, func() int { return rand.Intn(100) }), 10),
+		func(n int) bool { return n%2 == 0 })
+	words := stream.Map(ctx, evens, func(n int) string { return fmt.Sprint(n) })
+	joined := stream.Reduce(ctx, words, []string(nil), func(acc []string, w string) []string { return append(acc, w) })
+
+	fmt.Printf("even: %s\n", strings.Join(joined, ", "))
 }
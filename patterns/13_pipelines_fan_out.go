@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/patterns/stream"
+)
+
+/**
+The multiply stage of 11_pipelines_stream.go now takes a while for every value, so on its own goroutine it holds up
+the whole pipeline.  Fanned out over 4 goroutines the same work is done in about a quarter of the time.  Parallel
+sends the results as they are done, ParallelOrdered in the order the values came in.
+*/
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slowMultiply := func(i int) int {
+		time.Sleep(time.Duration(10+rand.Intn(40)) * time.Millisecond)
+		return i * 2
+	}
+	add := func(i int) int { return i + 1 }
+	numbers := func() <-chan int {
+		return stream.Take(ctx, stream.RepeatFn(ctx, counter()), 12)
+	}
+
+	start := time.Now()
+	for v := range stream.Map(ctx, stream.Map(ctx, numbers(), slowMultiply), add) {
+		fmt.Printf("%d ", v)
+	}
+	fmt.Printf("\none goroutine: %v\n", time.Since(start).Round(10*time.Millisecond))
+
+	start = time.Now()
+	for v := range stream.Map(ctx, stream.Parallel(ctx, numbers(), 4, slowMultiply), add) {
+		fmt.Printf("%d ", v)
+	}
+	fmt.Printf("\nfanned out, unordered: %v\n", time.Since(start).Round(10*time.Millisecond))
+
+	start = time.Now()
+	for v := range stream.Map(ctx, stream.ParallelOrdered(ctx, numbers(), 4, slowMultiply), add) {
+		fmt.Printf("%d ", v)
+	}
+	fmt.Printf("\nfanned out, ordered: %v\n", time.Since(start).Round(10*time.Millisecond))
+}
+
+func counter() func() int {
+	i := 0
+	return func() int {
+		i++
+		return i
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/patterns/stream"
+)
+
+/**
+Tee sends a stream to a fast and a slow consumer.  Neither loses a value, and the fast one has to wait for the slow one.
+
+Bridge reads a stream of streams as one.  Some of the inner streams close early, that just moves Bridge on to the
+next one.  Cancelling ctx halfway through stops every goroutine involved, which the goroutine count shows.
+*/
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fast, slow := stream.Tee(ctx, stream.Take(ctx, stream.Repeat(ctx, 1, 2, 3), 6))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for v := range slow {
+			time.Sleep(50 * time.Millisecond)
+			fmt.Printf("slow got %d\n", v)
+		}
+	}()
+	start := time.Now()
+	for v := range fast {
+		fmt.Printf("fast got %d after %v\n", v, time.Since(start).Round(10*time.Millisecond))
+	}
+	<-done
+
+	before := runtime.NumGoroutine()
+	bridgeCtx, stop := context.WithCancel(ctx)
+	chans := make(chan (<-chan int))
+	go func() {
+		defer close(chans)
+		for i := 0; ; i++ {
+			c := make(chan int)
+			go func(i int) {
+				defer close(c)
+				for j := 0; j < 3; j++ {
+					if i%2 == 1 && j == 1 {
+						return // this upstream closes early
+					}
+					select {
+					case <-bridgeCtx.Done():
+						return
+					case c <- i*10 + j:
+					}
+				}
+			}(i)
+			select {
+			case <-bridgeCtx.Done():
+				return
+			case chans <- c:
+			}
+		}
+	}()
+	for v := range stream.Take(bridgeCtx, stream.Bridge(bridgeCtx, chans), 8) {
+		fmt.Printf("%d ", v)
+	}
+	fmt.Println()
+	stop()
+	time.Sleep(10 * time.Millisecond)
+	fmt.Printf("goroutines before bridging %d, after cancelling %d\n", before, runtime.NumGoroutine())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"scm.applatform.io/mob/go-concurrency/patterns/stream"
+)
+
+/**
+The stream stages of 11_pipelines_stream.go have nowhere to send an error.  Here the first stage parses numbers and
+fails on the ones that aren't.  By default the first failure stops every stage and Wait returns it.  A batch job
+collects the errors instead, gets the valid numbers through and all the errors from Wait.
+*/
+
+func main() {
+	input := []string{"1", "2", "three", "4", "five", "6"}
+
+	run := func(opts ...stream.PipelineOption) {
+		p := stream.NewPipeline(context.Background(), opts...)
+		numbers := stream.Stage(p, stream.Source(p, input...), func(ctx context.Context, s string) (int, error) {
+			return strconv.Atoi(s)
+		})
+		doubled := stream.Stage(p, numbers, func(ctx context.Context, i int) (int, error) {
+			return i * 2, nil
+		})
+		for v := range doubled {
+			fmt.Printf("%d ", v)
+		}
+		fmt.Printf("\nerr: %v\n", p.Wait())
+	}
+
+	fmt.Println("first error stops the pipeline:")
+	run()
+	fmt.Println("collecting errors:")
+	run(stream.WithCollectErrors())
+}
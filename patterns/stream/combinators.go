@@ -0,0 +1,87 @@
+package stream
+
+import "context"
+
+/**
+Channel combinators from the patterns following `or` in patterns/07_or_channel.go.
+
+OrDone lets a caller range over a channel it doesn't own without having to select on ctx at every step.  Tee sends
+every value of one stream to two consumers.  It only takes the next value once both have received the current one, so
+the slower consumer sets the pace for both, nothing is buffered or dropped.  Bridge flattens a stream of streams
+into one, reading each inner stream until it is closed before moving on to the next.
+*/
+
+// OrDone passes on the values of in until in is closed or ctx is done.
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Tee sends every value of in on both of the channels it returns.
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			out1, out2 := out1, out2 // local copies, set to nil once they have had the value
+			for i := 0; i < 2; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case out1 <- v:
+					out1 = nil
+				case out2 <- v:
+					out2 = nil
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Bridge passes on the values of every channel sent on chans, one channel after the other.
+func Bridge[T any](ctx context.Context, chans <-chan <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			var in <-chan T
+			select {
+			case <-ctx.Done():
+				return
+			case c, ok := <-chans:
+				if !ok {
+					return
+				}
+				in = c
+			}
+			for v := range OrDone(ctx, in) {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,171 @@
+package stream
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// noLeaks fails the test unless the goroutines started since before have all exited.
+func noLeaks(t *testing.T, before int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left running", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// values sends vs and closes the channel.
+func values[T any](ctx context.Context, vs ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range vs {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+func collect[T any](in <-chan T) []T {
+	var got []T
+	for v := range in {
+		got = append(got, v)
+	}
+	return got
+}
+
+func equal[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOrDoneStopsOnCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int) // never closed by its owner
+	out := OrDone(ctx, in)
+
+	go func() { in <- 1 }()
+	if v := <-out; v != 1 {
+		t.Fatalf("got %d, want 1", v)
+	}
+	cancel()
+	if _, ok := <-out; ok {
+		t.Fatal("got a value after cancelling")
+	}
+	noLeaks(t, before)
+}
+
+func TestTeeSlowConsumer(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx := context.Background()
+	fast, slow := Tee(ctx, values(ctx, 1, 2, 3, 4, 5))
+
+	slowGot := make(chan []int)
+	go func() {
+		var got []int
+		for v := range slow {
+			time.Sleep(5 * time.Millisecond)
+			got = append(got, v)
+		}
+		slowGot <- got
+	}()
+
+	start := time.Now()
+	fastGot := collect(fast)
+	// The fast consumer can only be a value ahead of the slow one, so it can't finish before the slow one has taken
+	// all but the last value.
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("fast consumer done after %v, want it held back by the slow one", elapsed)
+	}
+	want := []int{1, 2, 3, 4, 5}
+	if !equal(fastGot, want) {
+		t.Fatalf("fast consumer got %v, want %v", fastGot, want)
+	}
+	if got := <-slowGot; !equal(got, want) {
+		t.Fatalf("slow consumer got %v, want %v", got, want)
+	}
+	noLeaks(t, before)
+}
+
+func TestTeeCancelWithAbandonedConsumer(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	out1, _ := Tee(ctx, Repeat(ctx, 1)) // nobody reads the second stream
+
+	<-out1
+	cancel()
+	for range out1 {
+	}
+	noLeaks(t, before)
+}
+
+func TestBridgeUpstreamClosure(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx := context.Background()
+	chans := make(chan (<-chan int))
+	go func() {
+		defer close(chans) // the upstream closes after three streams
+		for i := 0; i < 3; i++ {
+			c := make(chan int, 2)
+			c <- i * 10
+			if i != 1 {
+				c <- i*10 + 1
+			}
+			close(c)
+			chans <- c
+		}
+		empty := make(chan int)
+		close(empty)
+		chans <- empty
+	}()
+
+	got := collect(Bridge(ctx, chans))
+	if want := []int{0, 1, 10, 20, 21}; !equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	noLeaks(t, before)
+}
+
+func TestBridgeCancelMidStream(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	chans := make(chan (<-chan int))
+	go func() {
+		inner := make(chan int) // never closed, the bridge must still give up on it
+		select {
+		case chans <- inner:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case inner <- 1:
+		case <-ctx.Done():
+		}
+	}()
+
+	out := Bridge(ctx, chans)
+	if v := <-out; v != 1 {
+		t.Fatalf("got %d, want 1", v)
+	}
+	cancel()
+	for range out {
+	}
+	noLeaks(t, before)
+}
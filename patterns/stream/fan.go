@@ -0,0 +1,121 @@
+package stream
+
+import (
+	"context"
+	"sync"
+)
+
+/**
+A stage runs on a single goroutine, so the slowest stage sets the pace of the whole pipeline.  Fanning out starts
+several copies of a slow stage reading from the same input, fanning in merges what they send back into one stream.
+
+Merging gives up on order: a value that took little time overtakes one that took long.  ParallelOrdered puts them
+back in the order they came in, holding on to at most 2n values that are ready before their turn.
+*/
+
+// FanOut starts n copies of fn, each taking values from in and sending their results on a channel of its own.
+func FanOut[T, U any](ctx context.Context, in <-chan T, n int, fn func(T) U) []<-chan U {
+	if n < 1 {
+		n = 1
+	}
+	outs := make([]<-chan U, n)
+	for i := range outs {
+		outs[i] = Map(ctx, in, fn)
+	}
+	return outs
+}
+
+// FanIn merges ins into one stream, which is closed once all of ins are.
+func FanIn[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func(in <-chan T) {
+			defer wg.Done()
+			for v := range in {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Parallel sends fn of every value of in, computed by n goroutines, in the order they are done.
+func Parallel[T, U any](ctx context.Context, in <-chan T, n int, fn func(T) U) <-chan U {
+	return FanIn(ctx, FanOut(ctx, in, n, fn)...)
+}
+
+type indexed[T any] struct {
+	i int
+	v T
+}
+
+// ParallelOrdered sends fn of every value of in, computed by n goroutines, in the order of in.
+func ParallelOrdered[T, U any](ctx context.Context, in <-chan T, n int, fn func(T) U) <-chan U {
+	if n < 1 {
+		n = 1
+	}
+	window := make(chan struct{}, 2*n) // a place for every value taken but not yet sent on
+	numbered := make(chan indexed[T])
+	go func() {
+		defer close(numbered)
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case window <- struct{}{}:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case numbered <- indexed[T]{i, v}:
+				}
+			}
+		}
+	}()
+
+	results := Parallel(ctx, numbered, n, func(v indexed[T]) indexed[U] {
+		return indexed[U]{v.i, fn(v.v)}
+	})
+
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		pending := make(map[int]U)
+		next := 0
+		for r := range results {
+			pending[r.i] = r.v
+			for {
+				v, ok := pending[next]
+				if !ok {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+				delete(pending, next)
+				next++
+				<-window
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelSendsEveryValue(t *testing.T) {
+	ctx := context.Background()
+	got := collect(Parallel(ctx, values(ctx, 1, 2, 3, 4, 5, 6, 7, 8), 3, func(v int) int { return v * 10 }))
+	sort.Ints(got)
+	if want := []int{10, 20, 30, 40, 50, 60, 70, 80}; !equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestParallelOrderedResequences(t *testing.T) {
+	ctx := context.Background()
+	in := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	// Earlier values take longer, so they finish after the ones behind them.
+	slowFirst := func(v int) int {
+		time.Sleep(time.Duration(len(in)-v) * time.Millisecond)
+		return v
+	}
+	for _, n := range []int{1, 3, len(in)} {
+		if got := collect(ParallelOrdered(ctx, values(ctx, in...), n, slowFirst)); !equal(got, in) {
+			t.Errorf("%d goroutines: got %v, want %v", n, got, in)
+		}
+	}
+}
+
+func TestParallelOrderedHoldsAtMostTwiceN(t *testing.T) {
+	const n = 2
+	ctx := context.Background()
+	var started atomic.Int32
+	release := make(chan struct{})
+	out := ParallelOrdered(ctx, values(ctx, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9), n, func(v int) int {
+		started.Add(1)
+		if v == 0 {
+			<-release // everything behind the first value has to wait for its turn
+		}
+		return v
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	if got := started.Load(); got > 2*n {
+		t.Fatalf("%d values taken while the first is stuck, want at most %d", got, 2*n)
+	}
+	close(release)
+	if got, want := collect(out), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestParallelOrderedStopsOnCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := ParallelOrdered(ctx, Repeat(ctx, 1, 2, 3), 4, func(v int) int { return v })
+	for i := 0; i < 10; i++ {
+		<-out
+	}
+	cancel() // and walk away without draining out
+	noLeaks(t, before)
+}
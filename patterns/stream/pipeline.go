@@ -0,0 +1,161 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/**
+The stages so far can't fail.  A Pipeline runs stages that can: the first error cancels the pipeline's context, which
+stops every stage upstream and downstream of the one that failed, and Wait returns it once they have all stopped.
+
+Batch jobs would rather skip a bad item than give up on all of them.  With WithCollectErrors a stage that fails on an
+item leaves it out and carries on, and Wait returns all the errors joined together.
+
+The caller must drain the last stage, or cancel the context the pipeline was started with, before calling Wait,
+otherwise the stages are left waiting to send their values.
+*/
+
+// Result holds either the value of an item or the error it failed with, for streams that pass errors on to the
+// consumer rather than fail.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+type Pipeline struct {
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	collect bool
+	wg      sync.WaitGroup
+
+	m    sync.Mutex
+	errs []error
+}
+
+type PipelineOption func(*Pipeline)
+
+// WithCollectErrors makes stages skip the items they fail on instead of stopping the pipeline.
+func WithCollectErrors() PipelineOption {
+	return func(p *Pipeline) {
+		p.collect = true
+	}
+}
+
+// NewPipeline starts a pipeline that stops when ctx is done.
+func NewPipeline(ctx context.Context, opts ...PipelineOption) *Pipeline {
+	p := &Pipeline{parent: ctx}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Context is done once the pipeline has failed or ctx it was started with is done.
+func (p *Pipeline) Context() context.Context { return p.ctx }
+
+// Go runs fn as part of the pipeline.  An error returned by fn stops the pipeline, even when collecting errors.
+func (p *Pipeline) Go(fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := fn(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// Wait waits for every stage to stop and returns the first error, or all of them when collecting errors.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	switch len(p.errs) {
+	case 0:
+	case 1:
+		return p.errs[0]
+	default:
+		return errors.Join(p.errs...)
+	}
+	return p.parent.Err()
+}
+
+func (p *Pipeline) fail(err error) {
+	p.m.Lock()
+	if len(p.errs) == 0 || p.collect {
+		p.errs = append(p.errs, err)
+	}
+	p.m.Unlock()
+	p.cancel()
+}
+
+// itemFailed records err and reports whether the stage may carry on.
+func (p *Pipeline) itemFailed(err error) bool {
+	if !p.collect {
+		p.fail(err)
+		return false
+	}
+	p.m.Lock()
+	p.errs = append(p.errs, err)
+	p.m.Unlock()
+	return true
+}
+
+// Source sends values down the pipeline.
+func Source[T any](p *Pipeline, values ...T) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for _, v := range values {
+			select {
+			case <-ctx.Done():
+				return nil
+			case out <- v:
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Stage sends fn of every value of in.  When fn fails the pipeline stops, or the value is left out when collecting
+// errors.
+func Stage[T, U any](p *Pipeline, in <-chan T, fn func(context.Context, T) (U, error)) <-chan U {
+	out := make(chan U)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range OrDone(ctx, in) {
+			u, err := fn(ctx, v)
+			if err != nil {
+				if p.itemFailed(err) {
+					continue
+				}
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case out <- u:
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Sink calls fn with every value of in.  Like Stage, a failing fn stops the pipeline unless errors are collected.
+func Sink[T any](p *Pipeline, in <-chan T, fn func(context.Context, T) error) {
+	p.Go(func(ctx context.Context) error {
+		for v := range OrDone(ctx, in) {
+			if err := fn(ctx, v); err != nil && !p.itemFailed(err) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
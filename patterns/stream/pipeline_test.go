@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBadItem = errors.New("bad item")
+
+// failOn fails for the values in bad and doubles the others.
+func failOn(bad ...int) func(context.Context, int) (int, error) {
+	return func(_ context.Context, v int) (int, error) {
+		for _, b := range bad {
+			if v == b {
+				return 0, fmt.Errorf("item %d: %w", v, errBadItem)
+			}
+		}
+		return v * 2, nil
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline(context.Background())
+	var got []int
+	Sink(p, Stage(p, Source(p, 1, 2, 3), failOn()), func(_ context.Context, v int) error {
+		got = append(got, v)
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if want := []int{2, 4, 6}; !equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPipelineFirstErrorCancelsEveryStage(t *testing.T) {
+	before := runtime.NumGoroutine()
+	p := NewPipeline(context.Background())
+
+	var sourced atomic.Int32
+	source := make(chan int)
+	p.Go(func(ctx context.Context) error {
+		defer close(source)
+		for i := 0; ; i++ { // endless, so only the cancellation can stop it
+			select {
+			case <-ctx.Done():
+				return nil
+			case source <- i:
+				sourced.Add(1)
+			}
+		}
+	})
+	doubled := Stage(p, source, failOn(3, 5))
+	var sunk []int
+	Sink(p, doubled, func(_ context.Context, v int) error {
+		sunk = append(sunk, v)
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- p.Wait() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, errBadItem) || err.Error() != "item 3: bad item" {
+			t.Fatalf("got %v, want only the first error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the pipeline kept running after a stage failed")
+	}
+	if p.Context().Err() == nil {
+		t.Fatal("the pipeline context is still live")
+	}
+	if want := []int{0, 2, 4}; len(sunk) > len(want) || !equal(sunk, want[:len(sunk)]) {
+		t.Fatalf("sink got %v, want no more than %v", sunk, want)
+	}
+	noLeaks(t, before)
+}
+
+func TestPipelineSinkErrorStopsUpstream(t *testing.T) {
+	p := NewPipeline(context.Background())
+	var stageCalls atomic.Int32
+	in := make([]int, 1000)
+	for i := range in {
+		in[i] = i + 1
+	}
+	doubled := Stage(p, Source(p, in...), func(ctx context.Context, v int) (int, error) {
+		stageCalls.Add(1)
+		return v * 2, nil
+	})
+	Sink(p, doubled, func(_ context.Context, v int) error {
+		if v == 4 {
+			return errBadItem
+		}
+		return nil
+	})
+
+	if err := p.Wait(); !errors.Is(err, errBadItem) {
+		t.Fatalf("got %v, want %v", err, errBadItem)
+	}
+	if n := stageCalls.Load(); n > 10 {
+		t.Fatalf("stage ran %d times, want it to stop soon after the sink failed on the second value", n)
+	}
+}
+
+func TestPipelineCollectErrors(t *testing.T) {
+	p := NewPipeline(context.Background(), WithCollectErrors())
+	var got []int
+	Sink(p, Stage(p, Source(p, 1, 2, 3, 4, 5), failOn(2, 4)), func(_ context.Context, v int) error {
+		got = append(got, v)
+		return nil
+	})
+
+	err := p.Wait()
+	if want := []int{2, 6, 10}; !equal(got, want) {
+		t.Fatalf("got %v, want %v with the bad items left out", got, want)
+	}
+	if !errors.Is(err, errBadItem) || err.Error() != "item 2: bad item\nitem 4: bad item" {
+		t.Fatalf("got %q, want both errors joined", err)
+	}
+}
+
+func TestPipelineGoErrorStopsEvenWhenCollecting(t *testing.T) {
+	p := NewPipeline(context.Background(), WithCollectErrors())
+	p.Go(func(ctx context.Context) error { return errBadItem })
+	p.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if err := p.Wait(); !errors.Is(err, errBadItem) {
+		t.Fatalf("got %v, want %v", err, errBadItem)
+	}
+}
+
+func TestPipelineParentCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPipeline(ctx)
+	out := Stage(p, Source(p, 1, 2, 3), failOn())
+	<-out
+	cancel() // stop reading and cancel instead
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,69 @@
+package stream
+
+import "reflect"
+
+/**
+The recursive `or` in patterns/07_or_channel.go needs a goroutine for every two or three channels it combines.  Or
+watches all of its channels from a single goroutine with reflect.Select instead, whatever their number.  reflect.Select
+takes at most 65536 cases, so beyond that the channels are split in batches, each with a goroutine of its own, and the
+batches combined.  And has it easier: all of its channels have to close, so a single goroutine waits for one after the
+other.
+
+Like the original, the goroutine only exits once the result is closed, so combining channels that never close keeps it
+around for good.
+*/
+
+const maxSelectCases = 65536
+
+// Or returns a channel that is closed as soon as one of chans receives a value or is closed.  Without any channels it
+// is never closed.
+func Or[T any](chans ...<-chan T) <-chan struct{} {
+	if len(chans) > maxSelectCases {
+		return Or(batches(chans, Or[T])...)
+	}
+	out := make(chan struct{})
+	if len(chans) == 0 {
+		return out
+	}
+	go func() {
+		defer close(out)
+		reflect.Select(selectCases(chans))
+	}()
+	return out
+}
+
+// And returns a channel that is closed once all of chans are closed.  Values received on them are ignored.  Without
+// any channels it is closed straight away.
+func And[T any](chans ...<-chan T) <-chan struct{} {
+	out := make(chan struct{})
+	go func() {
+		defer close(out)
+		for _, c := range chans { // all of them have to close, so the order they are waited for doesn't matter
+			for range c {
+			}
+		}
+	}()
+	return out
+}
+
+func selectCases[T any](chans []<-chan T) []reflect.SelectCase {
+	cases := make([]reflect.SelectCase, len(chans))
+	for i, c := range chans {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)}
+	}
+	return cases
+}
+
+// batches combines chans in batches small enough for reflect.Select.
+func batches[T any](chans []<-chan T, combine func(...<-chan T) <-chan struct{}) []<-chan struct{} {
+	var combined []<-chan struct{}
+	for len(chans) > 0 {
+		n := len(chans)
+		if n > maxSelectCases {
+			n = maxSelectCases
+		}
+		combined = append(combined, combine(chans[:n]...))
+		chans = chans[n:]
+	}
+	return combined
+}
@@ -0,0 +1,142 @@
+package stream
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// recursiveOr is the or of patterns/07_or_channel.go, to compare against.
+func recursiveOr(channels ...<-chan struct{}) <-chan struct{} {
+	switch len(channels) {
+	case 0:
+		return nil
+	case 1:
+		return channels[0]
+	}
+	orDone := make(chan struct{})
+	go func() {
+		defer close(orDone)
+		switch len(channels) {
+		case 2:
+			select {
+			case <-channels[0]:
+			case <-channels[1]:
+			}
+		default:
+			select {
+			case <-channels[0]:
+			case <-channels[1]:
+			case <-channels[2]:
+			case <-recursiveOr(append(channels[3:], orDone)...):
+			}
+		}
+	}()
+	return orDone
+}
+
+func signals(n int) ([]chan struct{}, []<-chan struct{}) {
+	chans := make([]chan struct{}, n)
+	recv := make([]<-chan struct{}, n)
+	for i := range chans {
+		chans[i] = make(chan struct{})
+		recv[i] = chans[i]
+	}
+	return chans, recv
+}
+
+// closed reports whether c is closed within wait.
+func closed(c <-chan struct{}, wait time.Duration) bool {
+	select {
+	case <-c:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+func TestOr(t *testing.T) {
+	chans, recv := signals(5)
+	or := Or(recv...)
+	if closed(or, 10*time.Millisecond) {
+		t.Fatal("closed before any of its channels")
+	}
+	close(chans[3])
+	if !closed(or, time.Second) {
+		t.Fatal("still open after one of its channels closed")
+	}
+}
+
+func TestAnd(t *testing.T) {
+	chans, recv := signals(5)
+	and := And(recv...)
+	for _, c := range chans[1:] {
+		close(c)
+	}
+	if closed(and, 10*time.Millisecond) {
+		t.Fatal("closed with one of its channels still open")
+	}
+	close(chans[0])
+	if !closed(and, time.Second) {
+		t.Fatal("still open after all of its channels closed")
+	}
+	if !closed(And[struct{}](), time.Second) {
+		t.Fatal("And of no channels isn't closed")
+	}
+}
+
+func TestOrAndBatches(t *testing.T) {
+	n := maxSelectCases + 1000 // two batches
+	chans, recv := signals(n)
+
+	before := runtime.NumGoroutine()
+	or, and := Or(recv...), And(recv...)
+	if got := runtime.NumGoroutine() - before; got > 6 {
+		t.Fatalf("%d goroutines for %d channels, want a handful", got, n)
+	}
+
+	close(chans[n-1]) // in the second batch
+	if !closed(or, time.Second) {
+		t.Fatal("Or still open after a channel of its second batch closed")
+	}
+	for _, c := range chans[:n-1] {
+		if c != chans[maxSelectCases-1] { // the last of the first batch
+			close(c)
+		}
+	}
+	if closed(and, 10*time.Millisecond) {
+		t.Fatal("And closed with a channel of its first batch still open")
+	}
+	close(chans[maxSelectCases-1])
+	if !closed(and, time.Second) {
+		t.Fatal("And still open after all of its channels closed")
+	}
+}
+
+func benchmarkSignal(b *testing.B, combine func(...<-chan struct{}) <-chan struct{}, all bool) {
+	for _, n := range []int{10, 1000, 10000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				chans, recv := signals(n)
+				b.StartTimer()
+
+				done := combine(recv...)
+				if all {
+					for _, c := range chans {
+						close(c)
+					}
+				} else {
+					close(chans[n-1])
+				}
+				<-done
+			}
+		})
+	}
+}
+
+func BenchmarkRecursiveOr(b *testing.B) { benchmarkSignal(b, recursiveOr, false) }
+func BenchmarkOr(b *testing.B)          { benchmarkSignal(b, Or[struct{}], false) }
+func BenchmarkAnd(b *testing.B)         { benchmarkSignal(b, And[struct{}], true) }
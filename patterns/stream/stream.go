@@ -0,0 +1,123 @@
+package stream
+
+import "context"
+
+/**
+Type safe versions of the generators and stages in patterns/12_pipelines_generators.go.  Every stage follows the same
+rules as the originals: it takes a context, starts a goroutine that owns and closes the channel it returns, and stops as
+soon as the context is done or its input is closed.
+*/
+
+// Repeat sends values over and over again until ctx is done.
+func Repeat[T any](ctx context.Context, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		if len(values) == 0 {
+			return
+		}
+		for {
+			for _, v := range values {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// RepeatFn sends the result of calling fn over and over again until ctx is done.
+func RepeatFn[T any](ctx context.Context, fn func() T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- fn():
+			}
+		}
+	}()
+	return out
+}
+
+// Take passes on the first n values of in.
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			var v T
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				if !ok {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// Map sends fn of every value of in.
+func Map[T, U any](ctx context.Context, in <-chan T, fn func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range in {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- fn(v):
+			}
+		}
+	}()
+	return out
+}
+
+// Filter passes on the values of in that keep returns true for.
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range in {
+			if !keep(v) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// Reduce folds the values of in into initial with fn, until in is closed or ctx is done.
+func Reduce[T, A any](ctx context.Context, in <-chan T, initial A, fn func(A, T) A) A {
+	acc := initial
+	for {
+		select {
+		case <-ctx.Done():
+			return acc
+		case v, ok := <-in:
+			if !ok {
+				return acc
+			}
+			acc = fn(acc, v)
+		}
+	}
+}
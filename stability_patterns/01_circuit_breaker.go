@@ -4,73 +4,73 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sync"
 	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
 )
 
 /**
 Circuit Breaker automatically degrades service functions in response to a likely fault, preventing larger or cascading
 failures by eliminating recurring errors and providing reasonable error responses.
-*/
 
-/**
-Define the signature of the function interacting with you upstream service.
+See resilience/breaker.go for the closed, open and half-open states and the strategies used to trip the circuit.
 */
-type circuitFn func(context.Context) (string, error)
-
-func Breaker(circuit circuitFn, failureThreshold uint) circuitFn {
-	var consecutiveFailures = 0
-	var lastAttempt = time.Now()
-	var m sync.RWMutex
-
-	return func(ctx context.Context) (string, error) {
-		m.RLock() //establish a read lock
-		d := consecutiveFailures - int(failureThreshold)
-		if d >= 0 {
-			shouldRetryAt := lastAttempt.Add(time.Second * 2 << d)
-			if !time.Now().After(shouldRetryAt) {
-				m.RUnlock()
-				return "", errors.New("service unreachable")
-			}
-		}
-		m.RUnlock()                   // Release the read lock
-		response, err := circuit(ctx) // Issue request
-
-		m.Lock() // lock around the shared resource
-		defer m.Unlock()
-
-		lastAttempt = time.Now() // Record time of the attempt
-		if err != nil {          // Circuit returned an error, so we count the failures and return
-			consecutiveFailures++
-			return response, err
-		}
-
-		consecutiveFailures = 0 // reset failures
-		return response, nil
-	}
-}
 
 func main() {
 	attempts := 0
+	notFound := errors.New("404 Not found")
 	doSomething := func(ctx context.Context) (string, error) {
-		if attempts < 3 {
+		if attempts == 0 {
+			attempts++
+			return "", notFound // the caller's problem, so it doesn't count against the circuit
+		}
+		if attempts < 4 {
 			attempts++
 			return "", errors.New("500 Service unavailable")
 		}
 		return "It Works!!", nil
 	}
 
-	fn := Breaker(doSomething, 2)
+	cb := resilience.NewCircuitBreaker(doSomething, 2,
+		resilience.WithHalfOpenProbes(1),
+		resilience.WithFailureClassifier(resilience.Is(notFound, resilience.Permanent)),
+	)
 
 	for {
-		v, err := fn(context.Background())
+		v, err := cb.Execute(context.Background())
 		if err == nil {
 			fmt.Println(v)
 			break
 		} else {
-			fmt.Println(err)
+			fmt.Printf("%v (circuit %v)\n", err, cb.State())
 		}
 		time.Sleep(1 * time.Second)
 	}
 
+	slidingWindow()
+}
+
+// An upstream failing 2 out of every 5 calls never trips a consecutive failure breaker, but it does trip one that
+// looks at the failure rate of the last 5 calls.
+func slidingWindow() {
+	calls := 0
+	flaky := func(ctx context.Context) (string, error) {
+		calls++
+		if calls%5 < 2 {
+			return "", errors.New("500 Service unavailable")
+		}
+		return "It Works!!", nil
+	}
+
+	cb := resilience.NewCircuitBreaker(flaky, 0, resilience.WithCountWindow(5, resilience.WindowConfig{
+		MinimumCalls:          5,
+		FailureRateThreshold:  40,
+		SlowCallRateThreshold: 50,
+		SlowCallDuration:      time.Second,
+	}))
+
+	for i := 0; i < 8; i++ {
+		_, err := cb.Execute(context.Background())
+		fmt.Printf("call %d: err=%v circuit=%v\n", i+1, err, cb.State())
+	}
 }
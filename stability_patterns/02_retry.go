@@ -4,31 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"time"
-)
-
-type effectorFn func(context.Context) (string, error)
 
-func retry(effector effectorFn, retries int, delay time.Duration) effectorFn {
-	return func(ctx context.Context) (string, error) {
-		for r := 0; ; r++ {
-			response, err := effector(ctx)
-			if err == nil || r >= retries {
-				return response, err
-			}
-			log.Printf("Attempt %d failed; retrying in %v", r+1, delay)
-			select {
-			case <-time.After(delay):
-			case <-ctx.Done():
-				return "", ctx.Err()
-			}
-		}
-	}
-}
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
 
 func main() {
 	var count int
+	errValidation := errors.New("validation error")
 
 	emulateTransientError := func(ctx context.Context) (string, error) {
 		count++
@@ -40,7 +27,101 @@ func main() {
 		}
 	}
 
-	r := retry(emulateTransientError, 5, 2*time.Second)
+	r := resilience.Retry(emulateTransientError, 5, 2*time.Second, resilience.WithObserver(resilience.LogObserver(nil)))
+
+	res, err := r(context.Background())
+
+	fmt.Println(res, err)
+
+	// A fixed delay makes every client retry in lockstep.  Exponential backoff with full jitter spreads them out, the
+	// seeded source makes the delays the same on every run.
+	count = 0
+	r = resilience.Retry(emulateTransientError, 5, 0,
+		resilience.WithBackoff(resilience.FullJitter(500*time.Millisecond, rand.New(rand.NewSource(42)))),
+		resilience.WithMaxDelay(3*time.Second),
+		resilience.WithMaxElapsed(10*time.Second),
+		resilience.WithObserver(resilience.StructuredLogObserver(slog.Default())),
+	)
+
+	res, err = r(context.Background())
+
+	fmt.Println(res, err)
+
+	emulateValidationError := func(ctx context.Context) (string, error) {
+		return "", fmt.Errorf("field name: %w", errValidation)
+	}
+
+	r = resilience.Retry(emulateValidationError, 5, 2*time.Second, resilience.WithRetryClassifier(
+		resilience.Is(errValidation, resilience.Permanent), // retrying won't fix the request, so give up straight away
+	))
+
+	res, err = r(context.Background())
+
+	fmt.Println(res, err)
+
+	ping := func(ctx context.Context) (struct{}, error) { // effectors with nothing to return use struct{}
+		return struct{}{}, nil
+	}
+
+	_, err = resilience.Retry(ping, 5, 2*time.Second)(context.Background())
+
+	fmt.Println("ping:", err)
+
+	retryAfter()
+	retryBudget()
+}
+
+// During an outage every retry adds to the load.  Wrappers sharing a budget stop retrying once retries make up more
+// than 10% of the recent requests, plus one retry a second.
+func retryBudget() {
+	outage := func(ctx context.Context) (string, error) {
+		return "", errors.New("500 Service unavailable")
+	}
+
+	budget := resilience.NewRetryBudget(0.1, 1, 10*time.Second)
+	for i := 0; i < 5; i++ {
+		r := resilience.Retry(outage, 3, 10*time.Millisecond, resilience.WithRetryBudget(budget))
+		_, err := r(context.Background())
+		fmt.Printf("caller %d: %v, budget exhausted: %v\n", i+1, err, errors.Is(err, resilience.ErrRetryBudgetExhausted))
+	}
+}
+
+// An upstream answering 503 with a Retry-After header tells us how long to stay away.  The effector passes that on
+// by wrapping its error in a RetryAfterError and retry waits for it, as long as it is under the ceiling.
+func retryAfter() {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "It Works!!")
+	}))
+	defer server.Close()
+
+	checkStatus := func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("unexpected status: %v", resp.Status)
+			if d, ok := resilience.ResponseRetryAfter(resp); ok {
+				return "", &resilience.RetryAfterError{Err: err, Delay: d}
+			}
+			return "", err
+		}
+		return resp.Status, nil
+	}
+
+	r := resilience.Retry(checkStatus, 3, 100*time.Millisecond, resilience.WithRetryAfterCeiling(5*time.Second))
 
 	res, err := r(context.Background())
 
@@ -3,52 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
-)
-
-type throttleEffector func(context.Context) (string, error)
-
-func throttle(e throttleEffector, max uint, refill uint, d time.Duration) throttleEffector {
-	var tokens = max
-	var once sync.Once
-
-	return func(ctx context.Context) (string, error) {
-		if ctx.Err() != nil {
-			return "", ctx.Err()
-		}
-
-		once.Do(func() {
-			ticker := time.NewTicker(d)
-
-			go func() {
-				defer ticker.Stop()
-
-				for {
-					select {
-					case <-ctx.Done():
-						return
-
-					case <-ticker.C:
-						t := tokens + refill
-						if t > max {
-							t = max
-						}
-						tokens = t
-					}
-				}
-			}()
-		})
-
-		if tokens <= 0 {
-			return "", fmt.Errorf("too many calls")
-		}
 
-		tokens--
-
-		return e(ctx)
-	}
-}
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
 
 func main() {
 
@@ -57,7 +15,7 @@ func main() {
 		return "string", nil
 	}
 
-	throttledFn := throttle(fn, 5, 5, time.Second*5)
+	throttledFn := resilience.Throttle(fn, 5, 5, time.Second*5)
 
 	attempt := 0
 	for {
@@ -73,4 +31,28 @@ func main() {
 		}
 	}
 
+	tokenBucket()
+}
+
+// The bucket behind throttle can also be used directly.  Besides failing fast with Allow, a caller can Wait for a
+// token or Reserve one and decide for itself what to do with the delay.
+func tokenBucket() {
+	bucket := resilience.NewTokenBucket(2, 1, 500*time.Millisecond)
+	defer bucket.Stop()
+
+	fmt.Println("allowed:", bucket.Allow(), bucket.Allow(), bucket.Allow()) // the third call finds the bucket empty
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := bucket.Wait(ctx)
+	fmt.Printf("waited %v for a token: %v\n", time.Since(start).Round(100*time.Millisecond), err)
+
+	r, err := bucket.Reserve()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("reserved a token usable in %v\n", r.Delay().Round(100*time.Millisecond))
+	r.Cancel() // changed our mind, give it back
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+Instead of nesting the wrappers by hand, declare them from the outside in and let the policy check the order.
+*/
+
+func main() {
+	var calls int32
+	slowUpstream := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1) // attempts run on their own goroutine under the timeout
+		select {
+		case <-time.After(300 * time.Millisecond): // slower than the per attempt timeout below
+			return "It Works!!", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	fn, err := resilience.NewPolicy[string]().
+		Retry(2, 100*time.Millisecond).
+		Breaker(5).
+		Throttle(10, 10, time.Second).
+		Timeout(100 * time.Millisecond).
+		Build(slowUpstream)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	_, err = fn(context.Background())
+	fmt.Printf("after %d calls: %v\n", atomic.LoadInt32(&calls), err)
+
+	var rejected *resilience.RejectedError
+	if errors.As(err, &rejected) {
+		fmt.Printf("rejected by the %v layer, timed out: %v\n", rejected.Layer, errors.Is(err, resilience.ErrTimeout))
+	}
+
+	// The same policy with a fallback to serve something while the upstream is struggling.
+	fn, _ = resilience.NewPolicy[string]().
+		Fallback(func(ctx context.Context, err error) (string, error) {
+			return "cached value", nil
+		}).
+		Retry(2, 100*time.Millisecond).
+		Timeout(100 * time.Millisecond).
+		Build(slowUpstream)
+
+	v, err := fn(context.Background())
+	fmt.Println(v, err)
+
+	// Layers declared in the wrong order are caught when the policy is built.
+	_, err = resilience.NewPolicy[string]().
+		Breaker(5).
+		Retry(2, 100*time.Millisecond).
+		Build(slowUpstream)
+	fmt.Println(err)
+}
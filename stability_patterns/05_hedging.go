@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+Every now and then the upstream takes a second to answer.  Rather than waiting for it, a second copy of the request is
+sent after 100ms and the first answer wins.  The slow copy sees its context cancelled.
+*/
+
+func main() {
+	var calls int32
+	read := func(ctx context.Context) (string, error) {
+		call := atomic.AddInt32(&calls, 1)
+		latency := 20 * time.Millisecond
+		if call%5 == 1 {
+			latency = time.Second // the tail we want to cut
+		}
+		select {
+		case <-time.After(latency):
+			return fmt.Sprintf("answer from call %d", call), nil
+		case <-ctx.Done():
+			fmt.Printf("call %d cancelled\n", call)
+			return "", ctx.Err()
+		}
+	}
+
+	hedged := resilience.Hedge(read, 2, 100*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		v, err := hedged(context.Background())
+		fmt.Printf("%v %v in %v\n", v, err, time.Since(start).Round(time.Millisecond))
+	}
+	time.Sleep(50 * time.Millisecond) // give the cancelled copy a moment to say so
+}
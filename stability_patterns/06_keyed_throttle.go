@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+Every tenant gets a bucket of its own, so one noisy tenant can't use up everybody's allowance.  The manual clock lets
+us skip forward in time instead of sleeping.
+*/
+
+type tenantKey struct{}
+
+func main() {
+	clock := resilience.NewManualClock(time.Now())
+	limiter := resilience.NewKeyedLimiter(2, 2, time.Second, time.Minute, resilience.WithClock(clock))
+
+	fn := func(ctx context.Context) (string, error) {
+		return "Called Function!!", nil
+	}
+	throttledFn := resilience.ThrottleByKey(fn, limiter, func(ctx context.Context) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant
+	})
+
+	for _, tenant := range []string{"noisy", "noisy", "noisy", "quiet"} {
+		ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+		_, err := throttledFn(ctx)
+		fmt.Printf("%s: %v\n", tenant, err)
+	}
+	fmt.Println("tenants:", limiter.Len())
+
+	clock.Advance(2 * time.Minute) // both tenants go quiet and their buckets are dropped
+	fmt.Println("tenants after 2 minutes:", limiter.Len())
+}
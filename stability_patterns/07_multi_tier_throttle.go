@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+An API allowing 2 calls a second, 5 a minute and 1000 a day.  A call has to get past all three tiers, and when it
+doesn't the error says which tier said no and when to come back.
+*/
+
+func main() {
+	clock := resilience.NewManualClock(time.Now())
+	limiter := resilience.NewMultiLimiter(
+		resilience.NewTier("per second", 2, time.Second, resilience.WithClock(clock)),
+		resilience.NewTier("per minute", 5, time.Minute, resilience.WithClock(clock)),
+		resilience.NewTier("per day", 1000, 24*time.Hour, resilience.WithClock(clock)),
+	)
+
+	for i := 1; i <= 12; i++ {
+		err := limiter.Take()
+		var tierErr *resilience.TierError
+		if errors.As(err, &tierErr) {
+			fmt.Printf("call %d: rejected by the %s tier, next call in %v\n", i, tierErr.Tier, tierErr.Delay)
+		} else {
+			fmt.Printf("call %d: %v\n", i, err)
+		}
+		clock.Advance(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+The same traffic, a call every 100ms for 3 seconds, against a limit of 5 calls a second with each algorithm.  The token
+bucket and GCRA allow a burst up front and then settle at the sustained rate.  The sliding window log never allows more
+than 5 calls in any second and the counter approximates that with a fraction of the memory.
+
+Because every limiter reads the time from the manual clock the run takes no time at all.
+*/
+
+func main() {
+	limiters := []struct {
+		name string
+		new  func(clock resilience.Clock) resilience.Limiter
+	}{
+		{"token bucket", func(c resilience.Clock) resilience.Limiter {
+			return resilience.NewTokenBucket(5, 1, 200*time.Millisecond, resilience.WithClock(c))
+		}},
+		{"sliding window log", func(c resilience.Clock) resilience.Limiter {
+			return resilience.NewSlidingWindowLog(5, time.Second, resilience.WithClock(c))
+		}},
+		{"sliding window counter", func(c resilience.Clock) resilience.Limiter {
+			return resilience.NewSlidingWindowCounter(5, time.Second, resilience.WithClock(c))
+		}},
+		{"gcra", func(c resilience.Clock) resilience.Limiter {
+			return resilience.NewGCRA(5, time.Second, 5, resilience.WithClock(c))
+		}},
+	}
+
+	fn := func(ctx context.Context) (string, error) {
+		return "Called Function!!", nil
+	}
+
+	for _, l := range limiters {
+		clock := resilience.NewManualClock(time.Now())
+		throttledFn := resilience.ThrottleWith(fn, l.new(clock))
+
+		timeline := ""
+		allowed := 0
+		for i := 0; i < 30; i++ {
+			if _, err := throttledFn(context.Background()); err != nil {
+				timeline += "."
+			} else {
+				timeline += "x"
+				allowed++
+			}
+			clock.Advance(100 * time.Millisecond)
+		}
+		fmt.Printf("%-24s %s %d allowed\n", l.name, timeline, allowed)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+An upstream that copes with about 10 calls at once: beyond that every extra call makes all of them slower.  Twenty
+clients hammer it through an adaptive limiter and each algorithm finds a limit close to what the upstream can take
+without being told what that is.
+*/
+
+func main() {
+	algorithms := []struct {
+		name      string
+		algorithm resilience.LimitAlgorithm
+	}{
+		{"aimd", resilience.AIMD(1, 100, 0.9, 30*time.Millisecond)},
+		{"vegas", resilience.Vegas(1, 100, 3, 6)},
+		{"gradient", resilience.Gradient(1, 100, 1.5)},
+	}
+
+	for _, a := range algorithms {
+		var inflight int32
+		upstream := func(ctx context.Context) (struct{}, error) {
+			n := atomic.AddInt32(&inflight, 1)
+			defer atomic.AddInt32(&inflight, -1)
+			latency := 10 * time.Millisecond
+			if n > 10 {
+				latency += time.Duration(n-10) * 5 * time.Millisecond // queueing once it is overloaded
+			}
+			time.Sleep(latency)
+			return struct{}{}, nil
+		}
+
+		limiter := resilience.NewAdaptiveLimiter(20, a.algorithm)
+		limited := resilience.Adaptive(upstream, limiter)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		var wg sync.WaitGroup
+		var rejected int32
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ctx.Err() == nil {
+					if _, err := limited(ctx); err != nil {
+						atomic.AddInt32(&rejected, 1)
+						time.Sleep(5 * time.Millisecond)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		cancel()
+
+		fmt.Printf("%-8s settled on a limit of %d, %d calls rejected\n", a.name, limiter.Limit(), rejected)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+The payments service hangs.  Without a bulkhead every request handler ends up stuck waiting on it, with one each
+dependency gets a compartment of its own: calls to payments are turned away once its compartment is full, while calls
+to the catalog carry on as usual.
+*/
+
+func main() {
+	bulkheads := resilience.NewBulkheads(
+		resilience.BulkheadConfig{MaxConcurrent: 5, MaxQueue: 5, QueueTimeout: 100 * time.Millisecond},
+		map[string]resilience.BulkheadConfig{
+			"payments": {MaxConcurrent: 2, MaxQueue: 1, QueueTimeout: 100 * time.Millisecond},
+		},
+	)
+
+	payments := resilience.Isolate(func(ctx context.Context) (string, error) {
+		select {
+		case <-time.After(time.Second): // hanging
+			return "paid", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}, bulkheads.Compartment("payments"))
+
+	catalog := resilience.Isolate(func(ctx context.Context) (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		return "catalog", nil
+	}, bulkheads.Compartment("catalog"))
+
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	results := map[string]int{}
+	for i := 0; i < 5; i++ {
+		for _, call := range []resilience.Effector[string]{payments, catalog} {
+			wg.Add(1)
+			go func(call resilience.Effector[string]) {
+				defer wg.Done()
+				v, err := call(context.Background())
+				m.Lock()
+				defer m.Unlock()
+				switch {
+				case errors.Is(err, resilience.ErrBulkheadFull):
+					results[err.Error()]++
+				case err != nil:
+					results["error: "+err.Error()]++
+				default:
+					results[v]++
+				}
+			}(call)
+		}
+	}
+	wg.Wait()
+
+	for outcome, n := range results {
+		fmt.Printf("%2d x %s\n", n, outcome)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+Requests arrive twice as fast as the service can handle them.  Rather than letting the queue grow until every request
+times out, the shedder notices the queue is standing, sheds what has queued for too long and turns sheddable work away
+at the door.  Critical requests get through.
+
+The shedder is just another wrapper, here it sits in front of a circuit breaker.
+*/
+
+func main() {
+	work := func(ctx context.Context) (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		return "done", nil
+	}
+
+	shedder := resilience.NewShedder(2, resilience.WithCoDel(5*time.Millisecond, 50*time.Millisecond), resilience.WithLIFO())
+	fn := resilience.Shed(resilience.Breaker(work, 5), shedder)
+
+	priorities := []resilience.Priority{resilience.Sheddable, resilience.Normal, resilience.Critical}
+	names := map[resilience.Priority]string{
+		resilience.Sheddable: "sheddable",
+		resilience.Normal:    "normal",
+		resilience.Critical:  "critical",
+	}
+
+	var m sync.Mutex
+	served := map[resilience.Priority]int{}
+	shed := map[resilience.Priority]int{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 400; i++ { // one request every 2.5ms, the service manages one every 5ms
+		p := priorities[i%len(priorities)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := fn(resilience.WithPriority(context.Background(), p))
+			m.Lock()
+			defer m.Unlock()
+			if errors.Is(err, resilience.ErrShed) {
+				shed[p]++
+			} else {
+				served[p]++
+			}
+		}()
+		time.Sleep(2500 * time.Microsecond)
+	}
+	wg.Wait()
+
+	for _, p := range priorities {
+		fmt.Printf("%-9s served %3d shed %3d\n", names[p], served[p], shed[p])
+	}
+}
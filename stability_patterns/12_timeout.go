@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+The upstream hangs and doesn't look at its context.  Timeout returns after 300ms regardless and hands the late answer
+to WithLateResult once the upstream gets round to it.
+
+Retried under a 1s deadline, the first attempt doesn't take the whole second: it gets a third of what is left, the
+second half of what is left after that, and the last one whatever remains.
+*/
+
+func main() {
+	hang := func(ctx context.Context) (string, error) {
+		time.Sleep(500 * time.Millisecond) // ignores ctx
+		return "too late", nil
+	}
+
+	call := resilience.Timeout(hang, 300*time.Millisecond, resilience.WithLateResult(func(v string, err error) {
+		fmt.Printf("late result %q dropped\n", v)
+	}))
+	start := time.Now()
+	_, err := call(context.Background())
+	fmt.Printf("%v in %v, is ErrTimeout: %v\n", err, time.Since(start).Round(10*time.Millisecond), errors.Is(err, resilience.ErrTimeout))
+	time.Sleep(300 * time.Millisecond)
+
+	slow := func(ctx context.Context) (string, error) {
+		attempt, max, _ := resilience.AttemptFrom(ctx)
+		deadline, _ := ctx.Deadline()
+		fmt.Printf("attempt %d of %d gets %v\n", attempt, max, time.Until(deadline).Round(10*time.Millisecond))
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	retried := resilience.Retry(resilience.Timeout(slow, 0), 2, 0)
+	_, err = retried(ctx)
+	fmt.Println(err)
+}
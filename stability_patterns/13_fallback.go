@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+The price service goes down after two calls and its circuit opens.  While the cached price is less than a second old
+it is served, after that the default price is.  Either way the answer is marked as degraded.  A price nobody has
+heard of is a 404, that is not a reason to fall back and it is returned as it is.
+*/
+
+func main() {
+	calls := 0
+	notFound := errors.New("404 Not found")
+	price := func(ctx context.Context) (int, error) {
+		calls++
+		if calls > 2 {
+			return 0, errors.New("500 Service unavailable")
+		}
+		return 100 + calls, nil
+	}
+
+	clock := resilience.NewManualClock(time.Now())
+	breaker := resilience.Breaker(price, 1)
+	withFallback := resilience.Fallback(breaker,
+		resilience.WithCachedValue[int](time.Second),
+		resilience.WithDefault(99),
+		resilience.WithFallbackClock[int](clock),
+	)
+
+	for i := 0; i < 6; i++ {
+		r, err := withFallback(context.Background())
+		fmt.Printf("price %d from %v (degraded %v, age %v): %v\n", r.Value, r.Source, r.Degraded(), r.Age, r.Err)
+		if err != nil {
+			fmt.Println(err)
+		}
+		clock.Advance(400 * time.Millisecond)
+	}
+
+	lookup := resilience.Fallback(func(ctx context.Context) (int, error) { return 0, notFound },
+		resilience.WithDefault(99),
+		resilience.WithFallbackClassifier[int](resilience.Is(notFound, resilience.Permanent)),
+	)
+	_, err := lookup(context.Background())
+	fmt.Println(err)
+}
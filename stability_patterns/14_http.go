@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"scm.applatform.io/mob/go-concurrency/stability_patterns/resilience"
+)
+
+/**
+The client wraps its transport in a policy instead of wrapping every call by hand.  The upstream fails the first
+request with a 503, which the GET survives through a retry while the POST, that mustn't be sent twice, gets the 503
+straight back.  A PUT is idempotent, so it is retried with its body sent again.
+
+The upstream itself sits behind a ThrottleHandler, so a burst of calls is answered with 429 and a Retry-After header.
+*/
+
+func main() {
+	var hits int32
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.Method, body)
+	})
+	server := httptest.NewServer(resilience.ThrottleHandler(upstream, resilience.NewTokenBucket(6, 1, time.Second)))
+	defer server.Close()
+
+	policy := resilience.NewPolicy[*http.Response]().
+		Retry(2, 50*time.Millisecond).
+		Breaker(5).
+		Timeout(time.Second)
+	client := &http.Client{Transport: resilience.NewTransport(nil, policy)}
+
+	show := func(resp *http.Response, err error) {
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Printf("%v %q retry after %q\n", resp.Status, strings.TrimSpace(string(body)), resp.Header.Get("Retry-After"))
+	}
+
+	show(client.Get(server.URL))
+	show(client.Post(server.URL, "text/plain", strings.NewReader("order")))
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPut, server.URL, strings.NewReader("update"))
+	show(client.Do(req))
+
+	for i := 0; i < 3; i++ {
+		show(http.Get(server.URL)) // no retries, to see the 429
+	}
+}
@@ -0,0 +1,229 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+/**
+Any fixed limit is wrong most of the time: too high when the upstream is struggling and too low when it is idle.  An
+adaptive limiter caps the number of calls in flight instead of their rate and moves that cap based on what it
+observes, much like TCP congestion control moves its window:
+
+  - AIMD grows the limit slowly while calls succeed and cuts it by a factor after a failed or too slow one.
+  - Vegas compares the latency of each call with the best latency seen so far.  The difference tells it how many calls
+    are queueing in the upstream, and it grows or shrinks the limit to keep that queue short.
+  - Gradient looks at the ratio between the best latency seen and the latest one.  When calls get slower than that
+    the limit comes down in proportion, when they are about as fast it creeps up.
+*/
+
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+// A LimitAlgorithm works out the next limit after a call finished.  inflight is the number of calls in flight when
+// the call started and dropped tells whether it failed.  The algorithms keep state, so give every limiter its own.
+type LimitAlgorithm interface {
+	Update(limit int, rtt time.Duration, inflight int, dropped bool) int
+}
+
+type limitBounds struct {
+	min, max int
+}
+
+func (b limitBounds) clamp(limit int) int {
+	if limit < b.min {
+		return b.min
+	}
+	if limit > b.max {
+		return b.max
+	}
+	return limit
+}
+
+type aimd struct {
+	limitBounds
+	backoff  float64
+	timeout  time.Duration
+	estimate float64
+	lastDrop time.Time
+}
+
+// AIMD grows the limit by one for every limit good calls and multiplies it by backoff, say 0.9, after a failed call
+// or one slower than timeout.  The calls that were in flight together fail together, so it backs off at most once
+// every timeout.
+func AIMD(min, max int, backoff float64, timeout time.Duration) LimitAlgorithm {
+	return &aimd{limitBounds: limitBounds{min, max}, backoff: backoff, timeout: timeout}
+}
+
+func (a *aimd) Update(limit int, rtt time.Duration, inflight int, dropped bool) int {
+	if a.estimate == 0 {
+		a.estimate = float64(limit)
+	}
+	if dropped || (a.timeout > 0 && rtt > a.timeout) {
+		if time.Since(a.lastDrop) >= a.timeout {
+			a.estimate = math.Max(float64(a.min), a.estimate*a.backoff)
+			a.lastDrop = time.Now()
+		}
+	} else if inflight*2 >= limit { // only grow when the limit is actually being used
+		a.estimate = math.Min(float64(a.max), a.estimate+1/a.estimate)
+	}
+	return a.clamp(int(a.estimate))
+}
+
+type vegas struct {
+	limitBounds
+	alpha, beta int // keep between alpha and beta calls queueing in the upstream
+	minRTT      time.Duration
+}
+
+// Vegas keeps the estimated number of calls queueing in the upstream between alpha and beta.
+func Vegas(min, max, alpha, beta int) LimitAlgorithm {
+	return &vegas{limitBounds: limitBounds{min, max}, alpha: alpha, beta: beta}
+}
+
+func (v *vegas) Update(limit int, rtt time.Duration, inflight int, dropped bool) int {
+	if dropped {
+		return v.clamp(limit / 2)
+	}
+	if rtt <= 0 {
+		return v.clamp(limit)
+	}
+	if v.minRTT == 0 || rtt < v.minRTT {
+		v.minRTT = rtt
+	}
+	queue := int(math.Ceil(float64(limit) * (1 - float64(v.minRTT)/float64(rtt))))
+	switch {
+	case queue < v.alpha:
+		limit++
+	case queue > v.beta:
+		limit--
+	}
+	return v.clamp(limit)
+}
+
+type gradient struct {
+	limitBounds
+	tolerance float64 // how much slower than the best latency a call may be before the limit comes down
+	minRTT    time.Duration
+	estimate  float64
+}
+
+// Gradient scales the limit by how the latest latency compares to the best one seen.  tolerance, say 1.5, is how much
+// slower than that calls may get before the limit starts coming down.
+func Gradient(min, max int, tolerance float64) LimitAlgorithm {
+	return &gradient{limitBounds: limitBounds{min, max}, tolerance: tolerance}
+}
+
+func (g *gradient) Update(limit int, rtt time.Duration, inflight int, dropped bool) int {
+	if g.estimate == 0 {
+		g.estimate = float64(limit)
+	}
+	if dropped {
+		g.estimate = math.Max(float64(g.min), g.estimate/2)
+		return g.clamp(int(g.estimate))
+	}
+	if rtt <= 0 || float64(inflight) < g.estimate/2 {
+		return g.clamp(int(g.estimate)) // the limit isn't being used, so the latency says nothing about it
+	}
+	if g.minRTT == 0 || rtt < g.minRTT {
+		g.minRTT = rtt
+	}
+
+	grad := math.Max(0.5, math.Min(1, g.tolerance*float64(g.minRTT)/float64(rtt)))
+	next := g.estimate*grad + math.Sqrt(g.estimate) // the square root leaves room to probe for a higher limit
+	g.estimate = g.estimate*0.9 + next*0.1          // smooth it out so one slow call doesn't halve the limit
+	g.estimate = math.Max(float64(g.min), math.Min(float64(g.max), g.estimate))
+	return g.clamp(int(g.estimate))
+}
+
+type adaptiveConfig struct {
+	classifier Classifier
+}
+
+type AdaptiveOption func(*adaptiveConfig)
+
+// WithDropClassifier decides which errors tell the algorithm the upstream is overloaded.  By default every error in
+// the Failure class does.
+func WithDropClassifier(classifiers ...Classifier) AdaptiveOption {
+	return func(c *adaptiveConfig) {
+		c.classifier = Chain(classifiers...)
+	}
+}
+
+type AdaptiveLimiter struct {
+	algorithm LimitAlgorithm
+	config    adaptiveConfig
+
+	m        sync.Mutex
+	limit    int
+	inflight int
+}
+
+// NewAdaptiveLimiter starts with initial calls allowed in flight and lets algorithm adjust it from there.
+func NewAdaptiveLimiter(initial int, algorithm LimitAlgorithm, opts ...AdaptiveOption) *AdaptiveLimiter {
+	var config adaptiveConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if initial < 1 {
+		initial = 1
+	}
+	return &AdaptiveLimiter{algorithm: algorithm, config: config, limit: initial}
+}
+
+// Limit is the number of calls currently allowed in flight.
+func (l *AdaptiveLimiter) Limit() int {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.limit
+}
+
+// InFlight is the number of calls currently in flight.
+func (l *AdaptiveLimiter) InFlight() int {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.inflight
+}
+
+// acquire lets a call in if there is room under the limit.  The returned function must be called with its outcome.
+func (l *AdaptiveLimiter) acquire() (func(rtt time.Duration, err error), bool) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.inflight >= l.limit {
+		return nil, false
+	}
+	l.inflight++
+	inflight := l.inflight
+
+	return func(rtt time.Duration, err error) {
+		dropped := err != nil && Classify(err, l.config.classifier).CountsAsFailure()
+
+		l.m.Lock()
+		defer l.m.Unlock()
+		l.inflight--
+		if next := l.algorithm.Update(l.limit, rtt, inflight, dropped); next >= 1 {
+			l.limit = next
+		}
+	}, true
+}
+
+// Adaptive lets only as many calls into e at once as l currently allows.  Calls over the limit fail straight away
+// with ErrLimitExceeded.
+func Adaptive[T any](e Effector[T], l *AdaptiveLimiter) Effector[T] {
+	return func(ctx context.Context) (T, error) {
+		release, ok := l.acquire()
+		if !ok {
+			var zero T
+			return zero, ErrLimitExceeded
+		}
+
+		start := time.Now()
+		err := errPanicked // what release sees if e panics
+		defer func() { release(time.Since(start), err) }()
+		var response T
+		response, err = e(ctx)
+		return response, err
+	}
+}
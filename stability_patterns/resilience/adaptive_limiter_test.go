@@ -0,0 +1,142 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveReleasesOnPanic(t *testing.T) {
+	l := NewAdaptiveLimiter(2, AIMD(1, 10, 0.5, time.Second))
+	e := Adaptive(func(ctx context.Context) (string, error) { panic("boom") }, l)
+
+	for i := 0; i < 5; i++ {
+		func() {
+			defer func() { recover() }()
+			e(context.Background())
+		}()
+	}
+	if got := l.InFlight(); got != 0 {
+		t.Fatalf("InFlight() = %d after the panics, want 0", got)
+	}
+	ok := Adaptive(func(ctx context.Context) (string, error) { return "ok", nil }, l)
+	if _, err := ok(context.Background()); err != nil {
+		t.Fatalf("call after the panics: %v", err)
+	}
+}
+
+func TestAdaptiveRejectsOverLimit(t *testing.T) {
+	l := NewAdaptiveLimiter(1, AIMD(1, 1, 0.5, time.Second))
+	started, unblock := make(chan struct{}), make(chan struct{})
+	e := Adaptive(func(ctx context.Context) (string, error) {
+		close(started)
+		<-unblock
+		return "ok", nil
+	}, l)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e(context.Background())
+	}()
+	<-started
+	if _, err := e(context.Background()); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got %v, want %v", err, ErrLimitExceeded)
+	}
+	close(unblock)
+	<-done
+}
+
+// feed reports n calls to a, each taking rtt, with the limit in use or not, and returns the limit it ends up with.
+func feed(a LimitAlgorithm, limit, n int, rtt time.Duration, busy, dropped bool) int {
+	for i := 0; i < n; i++ {
+		inflight := 1
+		if busy {
+			inflight = limit
+		}
+		limit = a.Update(limit, rtt, inflight, dropped)
+	}
+	return limit
+}
+
+func TestLimitAlgorithms(t *testing.T) {
+	algorithms := map[string]func() LimitAlgorithm{
+		"aimd":     func() LimitAlgorithm { return AIMD(1, 100, 0.5, 50*time.Millisecond) },
+		"vegas":    func() LimitAlgorithm { return Vegas(1, 100, 2, 4) },
+		"gradient": func() LimitAlgorithm { return Gradient(1, 100, 1.5) },
+	}
+	const up, same, down = 1, 0, -1
+	tests := []struct {
+		name    string
+		rtt     time.Duration
+		busy    bool
+		dropped bool
+		want    map[string]int
+	}{
+		{"fast and busy", 10 * time.Millisecond, true, false, map[string]int{"aimd": up, "vegas": up, "gradient": up}},
+		{"slow", 100 * time.Millisecond, true, false, map[string]int{"aimd": down, "vegas": down, "gradient": down}},
+		{"dropped", 10 * time.Millisecond, true, true, map[string]int{"aimd": down, "vegas": down, "gradient": down}},
+		// Only Vegas goes by latency alone; the others don't grow a limit that isn't being used.
+		{"fast and idle", 10 * time.Millisecond, false, false, map[string]int{"aimd": same, "vegas": up, "gradient": same}},
+	}
+	for _, tt := range tests {
+		for name, newAlgorithm := range algorithms {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				a := newAlgorithm()
+				start := feed(a, 20, 5, 10*time.Millisecond, true, false) // learn the best latency
+				got := feed(a, start, 50, tt.rtt, tt.busy, tt.dropped)
+
+				direction := same
+				if got > start {
+					direction = up
+				} else if got < start {
+					direction = down
+				}
+				if direction != tt.want[name] {
+					t.Fatalf("limit went from %d to %d, want direction %d", start, got, tt.want[name])
+				}
+			})
+		}
+	}
+}
+
+func TestLimitAlgorithmsStayWithinBounds(t *testing.T) {
+	algorithms := map[string]LimitAlgorithm{
+		"aimd":     AIMD(3, 30, 0.5, 0), // without a timeout every drop backs off
+		"vegas":    Vegas(3, 30, 2, 4),
+		"gradient": Gradient(3, 30, 1.5),
+	}
+	for name, a := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			if got := feed(a, 10, 1000, 10*time.Millisecond, true, false); got != 30 {
+				t.Errorf("after many good calls the limit is %d, want the max 30", got)
+			}
+			if got := feed(a, 30, 100, 10*time.Millisecond, true, true); got != 3 {
+				t.Errorf("after many dropped calls the limit is %d, want the min 3", got)
+			}
+		})
+	}
+}
+
+func TestAIMDBacksOffOncePerTimeout(t *testing.T) {
+	a := AIMD(1, 100, 0.5, time.Hour)
+	if got := feed(a, 40, 5, time.Millisecond, true, true); got != 20 {
+		t.Fatalf("limit %d after calls that failed together, want 20", got)
+	}
+}
+
+func TestAdaptiveLimiterFollowsAlgorithm(t *testing.T) {
+	l := NewAdaptiveLimiter(10, AIMD(1, 100, 0.5, time.Hour), WithDropClassifier(Is(errNotFound, Permanent)))
+	failing := Adaptive(func(ctx context.Context) (string, error) { return "", errUpstream }, l)
+	notFound := Adaptive(func(ctx context.Context) (string, error) { return "", errNotFound }, l)
+
+	notFound(context.Background())
+	if got := l.Limit(); got != 10 {
+		t.Fatalf("limit %d after a permanent error, want it left at 10", got)
+	}
+	failing(context.Background())
+	if got := l.Limit(); got != 5 {
+		t.Fatalf("limit %d after a failure, want it halved to 5", got)
+	}
+}
@@ -0,0 +1,131 @@
+package resilience
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/**
+Waiting the same fixed delay between attempts makes every client that failed at the same moment retry at the same
+moment too, so a fleet of clients hammers a recovering upstream in lockstep.  Growing the delay exponentially spreads
+the retries out over time and adding jitter spreads them out between clients.
+
+See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/ for a comparison of the jitter
+strategies.
+*/
+
+// Backoff returns how long to wait before retry number attempt, counting from 1.  prev is the wait used before the
+// previous retry, zero before the first one, and max caps the result when it is positive.
+type Backoff func(attempt int, prev, max time.Duration) time.Duration
+
+// Rand is the source of randomness for the jittered strategies.  *rand.Rand satisfies it.
+type Rand interface {
+	Int63n(n int64) int64
+}
+
+// Constant always waits d.
+func Constant(d time.Duration) Backoff {
+	return func(_ int, _, max time.Duration) time.Duration {
+		return capDelay(d, max)
+	}
+}
+
+// Linear waits base, 2*base, 3*base...
+func Linear(base time.Duration) Backoff {
+	return func(attempt int, _, max time.Duration) time.Duration {
+		if attempt > 0 && base > math.MaxInt64/time.Duration(attempt) {
+			return capDelay(math.MaxInt64, max)
+		}
+		return capDelay(base*time.Duration(attempt), max)
+	}
+}
+
+// Exponential waits base, 2*base, 4*base...
+func Exponential(base time.Duration) Backoff {
+	return func(attempt int, _, max time.Duration) time.Duration {
+		return capDelay(exponential(base, attempt), max)
+	}
+}
+
+// FullJitter waits a random time between zero and the exponential delay.
+func FullJitter(base time.Duration, r Rand) Backoff {
+	r = lockRand(r)
+	return func(attempt int, _, max time.Duration) time.Duration {
+		return between(r, 0, capDelay(exponential(base, attempt), max))
+	}
+}
+
+// EqualJitter waits half of the exponential delay plus a random time up to the other half.
+func EqualJitter(base time.Duration, r Rand) Backoff {
+	r = lockRand(r)
+	return func(attempt int, _, max time.Duration) time.Duration {
+		half := capDelay(exponential(base, attempt), max) / 2
+		return half + between(r, 0, half)
+	}
+}
+
+// DecorrelatedJitter waits a random time between base and three times the previous wait.
+func DecorrelatedJitter(base time.Duration, r Rand) Backoff {
+	r = lockRand(r)
+	return func(_ int, prev, max time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		hi := prev
+		if hi <= math.MaxInt64/3 {
+			hi *= 3
+		}
+		return capDelay(between(r, base, hi), max)
+	}
+}
+
+func exponential(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		if d > math.MaxInt64/2 {
+			return math.MaxInt64
+		}
+		d *= 2
+	}
+	return d
+}
+
+func capDelay(d, max time.Duration) time.Duration {
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
+
+// between returns a random duration in [lo, hi).
+func between(r Rand, lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(r.Int63n(int64(hi-lo)))
+}
+
+type globalRand struct{}
+
+func (globalRand) Int63n(n int64) int64 { return rand.Int63n(n) } // the top level functions are goroutine safe
+
+// lockedRand makes an injected source, which usually isn't goroutine safe, usable from concurrent retries.
+type lockedRand struct {
+	m sync.Mutex
+	r Rand
+}
+
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.r.Int63n(n)
+}
+
+func lockRand(r Rand) Rand {
+	if r == nil {
+		return globalRand{}
+	}
+	return &lockedRand{r: r}
+}
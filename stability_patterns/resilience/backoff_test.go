@@ -0,0 +1,123 @@
+package resilience
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// fixedRand always returns the same fraction of n, so the jittered strategies become predictable.
+type fixedRand float64
+
+func (f fixedRand) Int63n(n int64) int64 {
+	if f >= 1 {
+		return n - 1 // float64 can't hold every int64, so n-1 could round up to n or beyond
+	}
+	return int64(float64(n-1) * float64(f))
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		max     time.Duration
+		want    []time.Duration // for attempts 1, 2, 3...
+	}{
+		{"constant", Constant(time.Second), 0, []time.Duration{time.Second, time.Second, time.Second}},
+		{"constant capped", Constant(time.Second), 500 * time.Millisecond, []time.Duration{500 * time.Millisecond}},
+		{"linear", Linear(time.Second), 0, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+		{"linear capped", Linear(time.Second), 2 * time.Second, []time.Duration{time.Second, 2 * time.Second, 2 * time.Second}},
+		{"exponential", Exponential(time.Second), 0, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"exponential capped", Exponential(time.Second), 3 * time.Second, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+		{"full jitter low", FullJitter(time.Second, fixedRand(0)), 0, []time.Duration{0, 0, 0}},
+		{"full jitter high", FullJitter(time.Second, fixedRand(1)), 0, []time.Duration{time.Second - 1, 2*time.Second - 1, 4*time.Second - 1}},
+		{"full jitter capped", FullJitter(time.Second, fixedRand(1)), 3 * time.Second, []time.Duration{time.Second - 1, 2*time.Second - 1, 3*time.Second - 1}},
+		{"equal jitter low", EqualJitter(time.Second, fixedRand(0)), 0, []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second}},
+		{"equal jitter high", EqualJitter(time.Second, fixedRand(1)), 0, []time.Duration{time.Second - 1, 2*time.Second - 1, 4*time.Second - 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.backoff(i+1, 0, tt.max); got != want {
+					t.Errorf("attempt %d: got %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Rand
+		max  time.Duration
+		want []time.Duration // each wait is fed back in as prev
+	}{
+		{"low", fixedRand(0), 0, []time.Duration{time.Second, time.Second, time.Second}},
+		{"high", fixedRand(1), 0, []time.Duration{3*time.Second - 1, 9*time.Second - 4, 27*time.Second - 13}},
+		{"capped", fixedRand(1), 5 * time.Second, []time.Duration{3*time.Second - 1, 5 * time.Second, 5 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := DecorrelatedJitter(time.Second, tt.r)
+			var prev time.Duration
+			for i, want := range tt.want {
+				prev = b(i+1, prev, tt.max)
+				if prev != want {
+					t.Errorf("attempt %d: got %v, want %v", i+1, prev, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffIsReproducibleWithSeededRand(t *testing.T) {
+	strategies := map[string]func(Rand) Backoff{
+		"full jitter":         func(r Rand) Backoff { return FullJitter(10*time.Millisecond, r) },
+		"equal jitter":        func(r Rand) Backoff { return EqualJitter(10*time.Millisecond, r) },
+		"decorrelated jitter": func(r Rand) Backoff { return DecorrelatedJitter(10*time.Millisecond, r) },
+	}
+	waits := func(b Backoff) []time.Duration {
+		var ds []time.Duration
+		var prev time.Duration
+		for attempt := 1; attempt <= 10; attempt++ {
+			prev = b(attempt, prev, time.Minute)
+			ds = append(ds, prev)
+		}
+		return ds
+	}
+	for name, strategy := range strategies {
+		t.Run(name, func(t *testing.T) {
+			first := waits(strategy(rand.New(rand.NewSource(42))))
+			second := waits(strategy(rand.New(rand.NewSource(42))))
+			for i := range first {
+				if first[i] != second[i] {
+					t.Fatalf("attempt %d: %v then %v from the same seed", i+1, first[i], second[i])
+				}
+				if first[i] < 0 || first[i] > time.Minute {
+					t.Fatalf("attempt %d: %v is outside [0, %v]", i+1, first[i], time.Minute)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffDoesNotOverflow(t *testing.T) {
+	for name, b := range map[string]Backoff{
+		"linear":              Linear(time.Hour),
+		"exponential":         Exponential(time.Hour),
+		"full jitter":         FullJitter(time.Hour, fixedRand(1)),
+		"equal jitter":        EqualJitter(time.Hour, fixedRand(1)),
+		"decorrelated jitter": DecorrelatedJitter(time.Hour, fixedRand(1)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := b(math.MaxInt32, math.MaxInt64/2, 0); got < 0 {
+				t.Errorf("got %v, want a positive wait", got)
+			}
+			if got := b(math.MaxInt32, math.MaxInt64/2, time.Minute); got > time.Minute {
+				t.Errorf("got %v, want at most %v", got, time.Minute)
+			}
+		})
+	}
+}
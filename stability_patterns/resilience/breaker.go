@@ -0,0 +1,281 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/**
+Circuit Breaker automatically degrades service functions in response to a likely fault, preventing larger or cascading
+failures by eliminating recurring errors and providing reasonable error responses.
+
+The breaker is a small state machine:
+
+  - Closed: requests flow through to the upstream.  Outcomes are fed to a trip strategy and once it decides the
+    upstream is unhealthy the circuit opens.
+  - Open: requests fail fast without touching the upstream.  The circuit stays open for a backoff that doubles every
+    time the circuit re-opens.
+  - Half-open: once the backoff has expired only a limited number of probe requests are let through.  A successful
+    probe closes the circuit again, a failed probe re-opens it.  Everybody else keeps failing fast, so the upstream
+    is not flooded the moment it comes back.
+
+By default the circuit trips after `failureThreshold` consecutive failures.  That is easy to reason about but a single
+success resets the counter, so an upstream failing 40% of the time never trips it.  For those cases the breaker can
+instead look at a sliding window of recent calls, either the last N calls or the calls of the last N seconds, and trip
+when the percentage of failed or slow calls crosses a threshold.
+
+Only errors that the failure classifier puts in the `Failure` class count against the circuit.  Everything else, a 404
+or the caller cancelling its context, is treated like a success as far as the circuit is concerned.  The one exception
+is a probe cancelled by its caller: it proves nothing about the upstream, so it only makes way for another probe.
+*/
+
+var ErrOpen = errors.New("service unreachable")
+
+const maxBackoffShift = 10 // stop doubling the open backoff after this many consecutive trips
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+type breakerConfig struct {
+	probes     uint
+	backoff    time.Duration
+	strategy   func() tripStrategy
+	classifier Classifier
+	local      []error // raised in front of the upstream, so they never count against it
+	clock      Clock
+}
+
+type BreakerOption func(*breakerConfig)
+
+// WithHalfOpenProbes sets how many requests are let through while the circuit is half-open.  Defaults to 1.
+func WithHalfOpenProbes(n uint) BreakerOption {
+	return func(c *breakerConfig) {
+		if n > 0 {
+			c.probes = n
+		}
+	}
+}
+
+// WithOpenBackoff sets how long the circuit stays open the first time it trips.  Defaults to 2 seconds.
+func WithOpenBackoff(d time.Duration) BreakerOption {
+	return func(c *breakerConfig) {
+		if d > 0 {
+			c.backoff = d
+		}
+	}
+}
+
+// WithFailureClassifier decides which errors count against the circuit.  The default classifier is consulted after it.
+func WithFailureClassifier(classifiers ...Classifier) BreakerOption {
+	return func(c *breakerConfig) {
+		c.classifier = Chain(classifiers...)
+	}
+}
+
+// WithBreakerClock makes the breaker read the time from c instead of the system clock.
+func WithBreakerClock(c Clock) BreakerOption {
+	return func(config *breakerConfig) {
+		if c != nil {
+			config.clock = c
+		}
+	}
+}
+
+// ignoreLocal makes the breaker ignore errors raised by layers between it and the upstream, such as a throttle
+// turning calls away.  They are never failures, whatever the classifier says.
+func ignoreLocal(errs ...error) BreakerOption {
+	return func(c *breakerConfig) {
+		c.local = append(c.local, errs...)
+	}
+}
+
+// WithCountWindow trips the circuit on the failure or slow call rate of the last `size` calls.
+func WithCountWindow(size uint, window WindowConfig) BreakerOption {
+	return func(c *breakerConfig) {
+		if size == 0 {
+			size = 1
+		}
+		c.strategy = func() tripStrategy {
+			return &countWindow{WindowConfig: window, outcomes: make([]outcome, size)}
+		}
+	}
+}
+
+// WithTimeWindow trips the circuit on the failure or slow call rate of the calls made in the last `size`.
+func WithTimeWindow(size time.Duration, window WindowConfig) BreakerOption {
+	return func(c *breakerConfig) {
+		width := size / timeWindowBuckets
+		if width <= 0 {
+			width = 1
+		}
+		c.strategy = func() tripStrategy {
+			return &timeWindow{WindowConfig: window, width: width}
+		}
+	}
+}
+
+type CircuitBreaker[T any] struct {
+	circuit  Effector[T]
+	config   breakerConfig
+	strategy tripStrategy
+
+	m              sync.Mutex
+	state          State
+	generation     uint64 // bumped on every state change so late results from an old state are ignored
+	trips          uint   // consecutive times the circuit opened without closing in between
+	openedAt       time.Time
+	probesInFlight uint
+	probeSuccesses uint
+}
+
+// NewCircuitBreaker trips after failureThreshold consecutive failures unless a window option picks another strategy.
+func NewCircuitBreaker[T any](circuit Effector[T], failureThreshold uint, opts ...BreakerOption) *CircuitBreaker[T] {
+	config := breakerConfig{
+		probes:   1,
+		backoff:  2 * time.Second,
+		clock:    realClock{},
+		strategy: func() tripStrategy { return &consecutiveFailures{threshold: failureThreshold} },
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return &CircuitBreaker[T]{circuit: circuit, config: config, strategy: config.strategy()}
+}
+
+// Breaker wraps circuit in a circuit breaker, see NewCircuitBreaker.
+func Breaker[T any](circuit Effector[T], failureThreshold uint, opts ...BreakerOption) Effector[T] {
+	return NewCircuitBreaker(circuit, failureThreshold, opts...).Execute
+}
+
+// State reports the current state of the circuit, moving an expired open circuit to half-open.
+func (cb *CircuitBreaker[T]) State() State {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+	cb.expireOpen(cb.config.clock.Now())
+	return cb.state
+}
+
+func (cb *CircuitBreaker[T]) Execute(ctx context.Context) (T, error) {
+	generation, err := cb.admit()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	start := cb.config.clock.Now()
+	err = errPanicked // what record sees if the circuit panics, so a probe doesn't keep its slot forever
+	defer func() { cb.record(generation, cb.outcome(err, cb.config.clock.Now().Sub(start))) }()
+	var response T
+	response, err = cb.circuit(ctx) // Issue request
+	return response, err
+}
+
+func (cb *CircuitBreaker[T]) outcome(err error, d time.Duration) outcome {
+	if err == nil {
+		return outcome{duration: d}
+	}
+	for _, local := range cb.config.local {
+		if errors.Is(err, local) {
+			return outcome{unproven: true, duration: d}
+		}
+	}
+	failed := Classify(err, cb.config.classifier).CountsAsFailure()
+	return outcome{failed: failed, unproven: !failed && errors.Is(err, context.Canceled), duration: d}
+}
+
+// admit decides whether a request may go through and returns the generation it was admitted in.
+func (cb *CircuitBreaker[T]) admit() (uint64, error) {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	cb.expireOpen(cb.config.clock.Now())
+	switch cb.state {
+	case Open:
+		return 0, ErrOpen
+	case HalfOpen:
+		if cb.probesInFlight+cb.probeSuccesses >= cb.config.probes {
+			return 0, ErrOpen // enough probes are already on their way
+		}
+		cb.probesInFlight++
+	}
+	return cb.generation, nil
+}
+
+func (cb *CircuitBreaker[T]) record(generation uint64, o outcome) {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	if generation != cb.generation {
+		return // the circuit changed state while the request was in flight
+	}
+
+	switch cb.state {
+	case Closed:
+		if cb.strategy.record(o, cb.config.clock.Now()) {
+			cb.open()
+		}
+	case HalfOpen:
+		cb.probesInFlight--
+		if o.failed {
+			cb.open()
+			return
+		}
+		if o.unproven {
+			return // free the probe slot for another probe, but this one proved nothing
+		}
+		cb.probeSuccesses++
+		if cb.probeSuccesses >= cb.config.probes {
+			cb.setState(Closed)
+			cb.trips = 0
+		}
+	}
+}
+
+// expireOpen moves an open circuit to half-open once its backoff has passed.  Must be called with the lock held.
+func (cb *CircuitBreaker[T]) expireOpen(now time.Time) {
+	if cb.state != Open {
+		return
+	}
+	shift := cb.trips - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	shouldRetryAt := cb.openedAt.Add(cb.config.backoff << shift)
+	if now.After(shouldRetryAt) {
+		cb.setState(HalfOpen)
+	}
+}
+
+func (cb *CircuitBreaker[T]) open() {
+	cb.setState(Open)
+	cb.openedAt = cb.config.clock.Now()
+	cb.trips++
+}
+
+func (cb *CircuitBreaker[T]) setState(s State) {
+	cb.state = s
+	cb.generation++
+	cb.strategy.reset()
+	cb.probesInFlight = 0
+	cb.probeSuccesses = 0
+}
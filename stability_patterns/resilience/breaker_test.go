@@ -0,0 +1,230 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errUpstream = errors.New("500 Service unavailable")
+
+// upstream fails while fail is set.  A call with a non-nil block waits for it before answering.
+type upstream struct {
+	fail  bool
+	block chan struct{}
+	calls int32
+}
+
+func (u *upstream) call(ctx context.Context) (string, error) {
+	atomic.AddInt32(&u.calls, 1)
+	fail, block := u.fail, u.block
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if fail {
+		return "", errUpstream
+	}
+	return "ok", nil
+}
+
+func wantState[T any](t *testing.T, cb *CircuitBreaker[T], want State) {
+	t.Helper()
+	if got := cb.State(); got != want {
+		t.Fatalf("state %v, want %v", got, want)
+	}
+}
+
+func TestBreakerStateMachine(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	u := &upstream{fail: true}
+	cb := NewCircuitBreaker(u.call, 2, WithOpenBackoff(time.Second), WithBreakerClock(clock))
+
+	cb.Execute(context.Background())
+	wantState(t, cb, Closed)
+	cb.Execute(context.Background())
+	wantState(t, cb, Open)
+
+	if _, err := cb.Execute(context.Background()); !errors.Is(err, ErrOpen) {
+		t.Fatalf("got %v, want %v", err, ErrOpen)
+	}
+	if u.calls != 2 {
+		t.Fatalf("%d calls reached the upstream while open, want none", u.calls-2)
+	}
+
+	clock.Advance(time.Second)
+	wantState(t, cb, Open)
+	clock.Advance(time.Nanosecond)
+	wantState(t, cb, HalfOpen)
+
+	cb.Execute(context.Background()) // the probe fails, the backoff doubles
+	wantState(t, cb, Open)
+	clock.Advance(time.Second + time.Nanosecond)
+	wantState(t, cb, Open)
+	clock.Advance(time.Second)
+	wantState(t, cb, HalfOpen)
+
+	u.fail = false
+	if _, err := cb.Execute(context.Background()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	wantState(t, cb, Closed)
+
+	u.fail = true // closing reset the backoff
+	cb.Execute(context.Background())
+	cb.Execute(context.Background())
+	wantState(t, cb, Open)
+	clock.Advance(time.Second + time.Nanosecond)
+	wantState(t, cb, HalfOpen)
+}
+
+func TestBreakerLimitsProbes(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	u := &upstream{fail: true}
+	cb := NewCircuitBreaker(u.call, 1, WithOpenBackoff(time.Second), WithHalfOpenProbes(2), WithBreakerClock(clock))
+
+	cb.Execute(context.Background())
+	clock.Advance(2 * time.Second)
+	u.fail, u.block = false, make(chan struct{})
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := cb.Execute(context.Background())
+			done <- err
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		cb.m.Lock()
+		inflight := cb.probesInFlight
+		cb.m.Unlock()
+		if inflight == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d probes in flight, want 2", inflight)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := cb.Execute(context.Background()); !errors.Is(err, ErrOpen) {
+		t.Fatalf("third call while two probes are in flight: got %v, want %v", err, ErrOpen)
+	}
+	close(u.block)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("probe: %v", err)
+		}
+	}
+	wantState(t, cb, Closed)
+}
+
+func TestBreakerIgnoresResultsFromAnOldState(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	started, unblock := make(chan struct{}), make(chan struct{})
+	var calls int32
+	cb := NewCircuitBreaker(func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-unblock
+		}
+		return "", errUpstream
+	}, 1, WithOpenBackoff(time.Second), WithBreakerClock(clock))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cb.Execute(context.Background())
+	}()
+	<-started
+
+	cb.Execute(context.Background())
+	wantState(t, cb, Open)
+	close(unblock) // the slow call fails too, but was admitted while the circuit was still closed
+	<-done
+
+	clock.Advance(time.Second + time.Nanosecond) // a second trip would have doubled the backoff
+	wantState(t, cb, HalfOpen)
+}
+
+func TestBreakerClassifier(t *testing.T) {
+	notFound := errors.New("404 Not found")
+	cb := NewCircuitBreaker(func(ctx context.Context) (string, error) { return "", notFound }, 1,
+		WithFailureClassifier(Is(notFound, Permanent)))
+
+	for i := 0; i < 3; i++ {
+		if _, err := cb.Execute(context.Background()); !errors.Is(err, notFound) {
+			t.Fatalf("call %d: got %v, want %v", i+1, err, notFound)
+		}
+	}
+	wantState(t, cb, Closed)
+}
+
+func TestBreakerCancelledProbeDoesNotClose(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	u := &upstream{fail: true}
+	cb := NewCircuitBreaker(func(ctx context.Context) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return u.call(ctx)
+	}, 1, WithOpenBackoff(time.Second), WithBreakerClock(clock))
+
+	cb.Execute(context.Background())
+	wantState(t, cb, Open)
+	clock.Advance(2 * time.Second)
+	wantState(t, cb, HalfOpen)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cb.Execute(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled probe: got %v, want %v", err, context.Canceled)
+	}
+	wantState(t, cb, HalfOpen)
+
+	u.fail = false
+	if _, err := cb.Execute(context.Background()); err != nil {
+		t.Fatalf("second probe: got %v, want it let through", err)
+	}
+	wantState(t, cb, Closed)
+}
+
+func TestBreakerPanickingProbeFreesItsSlot(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	u := &upstream{fail: true}
+	panicking := false
+	cb := NewCircuitBreaker(func(ctx context.Context) (string, error) {
+		if panicking {
+			panic("boom")
+		}
+		return u.call(ctx)
+	}, 1, WithOpenBackoff(time.Second), WithBreakerClock(clock))
+
+	cb.Execute(context.Background())
+	clock.Advance(2 * time.Second)
+	wantState(t, cb, HalfOpen)
+
+	panicking = true
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recovered %v, want the probe's panic", r)
+			}
+		}()
+		cb.Execute(context.Background())
+	}()
+	wantState(t, cb, Open) // a panic is a failure like any other
+
+	panicking, u.fail = false, false
+	clock.Advance(3 * time.Second)
+	if _, err := cb.Execute(context.Background()); err != nil {
+		t.Fatalf("probe after the panic: got %v, want it let through", err)
+	}
+	wantState(t, cb, Closed)
+}
@@ -0,0 +1,124 @@
+package resilience
+
+import "time"
+
+type WindowConfig struct {
+	MinimumCalls          uint          // calls needed in the window before the rates are evaluated at all
+	FailureRateThreshold  float64       // percentage of failed calls that trips the circuit, 0 disables it
+	SlowCallRateThreshold float64       // percentage of slow calls that trips the circuit, 0 disables it
+	SlowCallDuration      time.Duration // calls taking longer than this are slow
+}
+
+type outcome struct {
+	failed   bool
+	unproven bool // the call says nothing about the upstream, eg. its caller gave up
+	duration time.Duration
+}
+
+// A trip strategy is told about every call made while the circuit is closed and decides when it should open.
+type tripStrategy interface {
+	record(o outcome, now time.Time) (trip bool)
+	reset()
+}
+
+type consecutiveFailures struct {
+	threshold uint
+	failures  uint
+}
+
+func (c *consecutiveFailures) record(o outcome, _ time.Time) bool {
+	if !o.failed {
+		c.failures = 0
+		return false
+	}
+	c.failures++
+	return c.failures >= c.threshold
+}
+
+func (c *consecutiveFailures) reset() { c.failures = 0 }
+
+type windowCounts struct {
+	calls, failures, slow uint
+}
+
+func (w *windowCounts) add(o outcome, slowCallDuration time.Duration, delta int) {
+	w.calls += uint(delta)
+	if o.failed {
+		w.failures += uint(delta)
+	}
+	if slowCallDuration > 0 && o.duration > slowCallDuration {
+		w.slow += uint(delta)
+	}
+}
+
+func (cfg WindowConfig) exceeded(w windowCounts) bool {
+	if w.calls == 0 || w.calls < cfg.MinimumCalls {
+		return false
+	}
+	failureRate := float64(w.failures) * 100 / float64(w.calls)
+	slowRate := float64(w.slow) * 100 / float64(w.calls)
+	return (cfg.FailureRateThreshold > 0 && failureRate >= cfg.FailureRateThreshold) ||
+		(cfg.SlowCallRateThreshold > 0 && slowRate >= cfg.SlowCallRateThreshold)
+}
+
+// countWindow keeps the outcome of the last len(outcomes) calls in a ring buffer along with running totals.
+type countWindow struct {
+	WindowConfig
+	outcomes []outcome
+	next     int
+	totals   windowCounts
+}
+
+func (c *countWindow) record(o outcome, _ time.Time) bool {
+	if c.totals.calls == uint(len(c.outcomes)) {
+		c.totals.add(c.outcomes[c.next], c.SlowCallDuration, -1) // the oldest call drops out of the window
+	}
+	c.outcomes[c.next] = o
+	c.next = (c.next + 1) % len(c.outcomes)
+	c.totals.add(o, c.SlowCallDuration, 1)
+	return c.exceeded(c.totals)
+}
+
+func (c *countWindow) reset() {
+	c.next = 0
+	c.totals = windowCounts{}
+}
+
+const timeWindowBuckets = 10
+
+// timeWindow splits the window into buckets so memory stays constant however many calls are made.
+type timeWindow struct {
+	WindowConfig
+	width   time.Duration
+	buckets [timeWindowBuckets]struct {
+		epoch int64
+		windowCounts
+	}
+}
+
+func (t *timeWindow) record(o outcome, now time.Time) bool {
+	epoch := now.UnixNano() / int64(t.width)
+	b := &t.buckets[epoch%timeWindowBuckets]
+	if b.epoch != epoch {
+		b.epoch = epoch
+		b.windowCounts = windowCounts{}
+	}
+	b.add(o, t.SlowCallDuration, 1)
+
+	var totals windowCounts
+	for _, b := range t.buckets {
+		if epoch-b.epoch < timeWindowBuckets {
+			totals.calls += b.calls
+			totals.failures += b.failures
+			totals.slow += b.slow
+		}
+	}
+	return t.exceeded(totals)
+}
+
+func (t *timeWindow) reset() {
+	for i := range t.buckets {
+		t.buckets[i].epoch = 0
+		t.buckets[i].windowCounts = windowCounts{}
+	}
+}
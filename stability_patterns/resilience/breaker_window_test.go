@@ -0,0 +1,112 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var (
+	okCall     = outcome{duration: time.Millisecond}
+	failedCall = outcome{failed: true, duration: time.Millisecond}
+	slowCall   = outcome{duration: time.Second}
+)
+
+func TestConsecutiveFailures(t *testing.T) {
+	s := &consecutiveFailures{threshold: 3}
+	now := time.Now()
+	for i, o := range []outcome{failedCall, failedCall, okCall, failedCall, failedCall} {
+		if s.record(o, now) {
+			t.Fatalf("tripped on outcome %d", i+1)
+		}
+	}
+	if !s.record(failedCall, now) {
+		t.Fatal("didn't trip on the third failure in a row")
+	}
+}
+
+func TestCountWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   WindowConfig
+		outcomes []outcome
+		trips    []bool
+	}{
+		{
+			name:     "minimum calls",
+			config:   WindowConfig{MinimumCalls: 3, FailureRateThreshold: 50},
+			outcomes: []outcome{failedCall, failedCall, okCall},
+			trips:    []bool{false, false, true},
+		},
+		{
+			name:     "failure rate",
+			config:   WindowConfig{FailureRateThreshold: 40},
+			outcomes: []outcome{okCall, okCall, okCall, okCall, failedCall, failedCall},
+			trips:    []bool{false, false, false, false, false, true}, // 1 in 5, then 2 in 5
+		},
+		{
+			name:     "old calls drop out",
+			config:   WindowConfig{FailureRateThreshold: 60},
+			outcomes: []outcome{failedCall, okCall, okCall, okCall, okCall, failedCall, failedCall, okCall},
+			trips:    []bool{true, false, false, false, false, false, false, false}, // never 3 in the last 5
+		},
+		{
+			name:     "slow calls",
+			config:   WindowConfig{MinimumCalls: 5, SlowCallRateThreshold: 50, SlowCallDuration: 100 * time.Millisecond},
+			outcomes: []outcome{slowCall, okCall, slowCall, okCall, slowCall},
+			trips:    []bool{false, false, false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &countWindow{WindowConfig: tt.config, outcomes: make([]outcome, 5)}
+			now := time.Now()
+			for i, o := range tt.outcomes {
+				if got := w.record(o, now); got != tt.trips[i] {
+					t.Fatalf("outcome %d: trip %v, want %v", i+1, got, tt.trips[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	w := &timeWindow{WindowConfig: WindowConfig{MinimumCalls: 4, FailureRateThreshold: 50}, width: time.Second}
+	now := time.Unix(1000, 0)
+
+	w.record(failedCall, now)
+	w.record(failedCall, now.Add(time.Second))
+	if w.record(okCall, now.Add(2*time.Second)) {
+		t.Fatal("tripped below the minimum number of calls")
+	}
+	// Ten seconds on the first failure has left the window, counting it would make 2 failures in 4 calls.
+	if w.record(okCall, now.Add(10*time.Second)) {
+		t.Fatal("counted a call older than the window")
+	}
+	if !w.record(failedCall, now.Add(10*time.Second)) { // 2 failures in 4 calls
+		t.Fatal("didn't trip at 50% failures")
+	}
+
+	w.reset()
+	if w.record(failedCall, now.Add(12*time.Second)) {
+		t.Fatal("remembered calls made before the reset")
+	}
+}
+
+func TestBreakerTripsOnFailureRate(t *testing.T) {
+	calls := 0
+	cb := NewCircuitBreaker(func(ctx context.Context) (string, error) {
+		calls++
+		if calls%5 < 2 {
+			return "", errUpstream
+		}
+		return "ok", nil
+	}, 0, WithCountWindow(5, WindowConfig{MinimumCalls: 5, FailureRateThreshold: 40}))
+
+	for i := 0; i < 4; i++ {
+		cb.Execute(context.Background())
+		wantState(t, cb, Closed)
+	}
+	cb.Execute(context.Background())
+	wantState(t, cb, Open)
+}
@@ -0,0 +1,165 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/**
+A ship's hull is split into compartments so that one leak doesn't sink the whole ship.  A bulkhead does the same for
+a process: every dependency gets a compartment with a fixed number of calls allowed at once and a short queue in front
+of it.  When one dependency hangs, only the goroutines calling it pile up, and only as far as its compartment allows,
+while calls to the other dependencies carry on.
+
+Calls that find the compartment and its queue full, or that wait in the queue for longer than the queue timeout, are
+turned away with a *BulkheadFullError.
+*/
+
+var ErrBulkheadFull = errors.New("bulkhead full")
+
+// BulkheadFullError is returned when a compartment turns a call away.
+type BulkheadFullError struct {
+	Name   string
+	Queued bool // whether the call waited in the queue, and timed out there, rather than finding the queue full
+}
+
+func (e *BulkheadFullError) Error() string {
+	reason := "queue full"
+	if e.Queued {
+		reason = "timed out in queue"
+	}
+	if e.Name == "" {
+		return fmt.Sprintf("%v: %s", ErrBulkheadFull, reason)
+	}
+	return fmt.Sprintf("%v: %s: %s", ErrBulkheadFull, e.Name, reason)
+}
+
+func (e *BulkheadFullError) Is(target error) bool { return target == ErrBulkheadFull }
+
+type BulkheadConfig struct {
+	MaxConcurrent int           // calls allowed into the compartment at once
+	MaxQueue      int           // calls allowed to wait for a place, 0 turns calls away as soon as it is full
+	QueueTimeout  time.Duration // how long a call may wait for a place, 0 waits for as long as its context allows
+}
+
+type Compartment struct {
+	name         string
+	queueTimeout time.Duration
+	slots        chan struct{}
+	queue        chan struct{}
+}
+
+func NewCompartment(name string, config BulkheadConfig) *Compartment {
+	if config.MaxConcurrent < 1 {
+		config.MaxConcurrent = 1
+	}
+	if config.MaxQueue < 0 {
+		config.MaxQueue = 0
+	}
+	return &Compartment{
+		name:         name,
+		queueTimeout: config.QueueTimeout,
+		slots:        make(chan struct{}, config.MaxConcurrent),
+		queue:        make(chan struct{}, config.MaxQueue),
+	}
+}
+
+func (c *Compartment) Name() string { return c.name }
+
+// InFlight is the number of calls currently inside the compartment.
+func (c *Compartment) InFlight() int { return len(c.slots) }
+
+// Queued is the number of calls currently waiting for a place.
+func (c *Compartment) Queued() int { return len(c.queue) }
+
+// Acquire takes a place in the compartment, queueing for one if need be.  release must be called once the call is
+// done.
+func (c *Compartment) Acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-c.slots }
+
+	select {
+	case c.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	select {
+	case c.queue <- struct{}{}:
+		defer func() { <-c.queue }()
+	default:
+		return nil, &BulkheadFullError{Name: c.name}
+	}
+
+	var timeout <-chan time.Time
+	if c.queueTimeout > 0 {
+		timer := time.NewTimer(c.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, &BulkheadFullError{Name: c.name, Queued: true}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Isolate only lets as many calls into e at once as compartment c allows.
+func Isolate[T any](e Effector[T], c *Compartment) Effector[T] {
+	return func(ctx context.Context) (T, error) {
+		release, err := c.Acquire(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		defer release()
+		return e(ctx)
+	}
+}
+
+// Bulkhead lets at most maxConcurrent calls into e at once, with up to maxQueue more waiting up to queueTimeout for
+// their turn.
+func Bulkhead[T any](e Effector[T], maxConcurrent int, maxQueue int, queueTimeout time.Duration) Effector[T] {
+	return Isolate(e, NewCompartment("", BulkheadConfig{
+		MaxConcurrent: maxConcurrent,
+		MaxQueue:      maxQueue,
+		QueueTimeout:  queueTimeout,
+	}))
+}
+
+// Bulkheads partitions a process into named compartments, usually one per dependency.  Compartments are created the
+// first time they are asked for, with their own configuration if they have one and the defaults otherwise.
+type Bulkheads struct {
+	defaults BulkheadConfig
+	configs  map[string]BulkheadConfig
+
+	m            sync.Mutex
+	compartments map[string]*Compartment
+}
+
+func NewBulkheads(defaults BulkheadConfig, configs map[string]BulkheadConfig) *Bulkheads {
+	return &Bulkheads{defaults: defaults, configs: configs, compartments: make(map[string]*Compartment)}
+}
+
+// Compartment returns the compartment called name.
+func (b *Bulkheads) Compartment(name string) *Compartment {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	c, ok := b.compartments[name]
+	if !ok {
+		config, ok := b.configs[name]
+		if !ok {
+			config = b.defaults
+		}
+		c = NewCompartment(name, config)
+		b.compartments[name] = c
+	}
+	return c
+}
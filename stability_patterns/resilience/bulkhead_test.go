@@ -0,0 +1,172 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fullError fails the test unless err is a *BulkheadFullError from compartment name with the given Queued.
+func fullError(t *testing.T, err error, name string, queued bool) {
+	t.Helper()
+	var full *BulkheadFullError
+	if !errors.As(err, &full) || !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("got %v, want a *BulkheadFullError", err)
+	}
+	if full.Name != name || full.Queued != queued {
+		t.Fatalf("got %+v, want name %q and Queued %v", full, name, queued)
+	}
+}
+
+// queueFor waits in c's queue in the background and reports the outcome on the returned channel once it has a slot,
+// releasing the slot straight away.
+func queueFor(t *testing.T, ctx context.Context, c *Compartment) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	before := c.Queued()
+	go func() {
+		release, err := c.Acquire(ctx)
+		if err == nil {
+			release()
+		}
+		result <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for c.Queued() == before {
+		if time.Now().After(deadline) {
+			t.Fatal("the call never joined the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return result
+}
+
+func TestCompartmentTurnsAwayWhenQueueIsFull(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxQueue int
+	}{
+		{"no queue", 0},
+		{"full queue", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCompartment("db", BulkheadConfig{MaxConcurrent: 1, MaxQueue: tt.maxQueue})
+			release, err := c.Acquire(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer release()
+			for i := 0; i < tt.maxQueue; i++ {
+				queueFor(t, context.Background(), c)
+			}
+
+			_, err = c.Acquire(context.Background())
+			fullError(t, err, "db", false)
+			if c.InFlight() != 1 || c.Queued() != tt.maxQueue {
+				t.Fatalf("%d in flight and %d queued, want 1 and %d", c.InFlight(), c.Queued(), tt.maxQueue)
+			}
+		})
+	}
+}
+
+func TestCompartmentQueueTimeout(t *testing.T) {
+	c := NewCompartment("db", BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 20 * time.Millisecond})
+	release, _ := c.Acquire(context.Background())
+	defer release()
+
+	start := time.Now()
+	_, err := c.Acquire(context.Background())
+	fullError(t, err, "db", true)
+	if took := time.Since(start); took < 20*time.Millisecond {
+		t.Fatalf("gave up after %v, want after the 20ms queue timeout", took)
+	}
+	if c.Queued() != 0 {
+		t.Fatalf("%d still queued, want the place in the queue given back", c.Queued())
+	}
+}
+
+func TestCompartmentCancelWhileQueued(t *testing.T) {
+	c := NewCompartment("db", BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1})
+	release, _ := c.Acquire(context.Background())
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := queueFor(t, ctx, c)
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if c.Queued() != 0 {
+		t.Fatalf("%d still queued, want 0", c.Queued())
+	}
+}
+
+func TestCompartmentQueuedCallGetsReleasedSlot(t *testing.T) {
+	c := NewCompartment("db", BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1})
+	release, _ := c.Acquire(context.Background())
+	result := queueFor(t, context.Background(), c)
+
+	release()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("got %v, want the queued call let in", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the queued call never got the slot")
+	}
+}
+
+func TestIsolateReleasesSlot(t *testing.T) {
+	c := NewCompartment("db", BulkheadConfig{MaxConcurrent: 1})
+	inside := -1
+	e := Isolate(func(ctx context.Context) (string, error) {
+		inside = c.InFlight()
+		return "", errUpstream
+	}, c)
+
+	for i := 0; i < 3; i++ {
+		if _, err := e(context.Background()); !errors.Is(err, errUpstream) {
+			t.Fatalf("call %d: got %v, want %v", i+1, err, errUpstream)
+		}
+		if inside != 1 || c.InFlight() != 0 {
+			t.Fatalf("call %d: %d in flight during the call and %d after, want 1 and 0", i+1, inside, c.InFlight())
+		}
+	}
+
+	panicking := Isolate(func(ctx context.Context) (string, error) { panic("boom") }, c)
+	func() {
+		defer func() { recover() }()
+		panicking(context.Background())
+	}()
+	if c.InFlight() != 0 {
+		t.Fatalf("%d in flight after a panic, want 0", c.InFlight())
+	}
+}
+
+func TestBulkheadsPerName(t *testing.T) {
+	b := NewBulkheads(BulkheadConfig{MaxConcurrent: 1}, map[string]BulkheadConfig{
+		"search": {MaxConcurrent: 3},
+	})
+
+	search := b.Compartment("search")
+	if b.Compartment("search") != search {
+		t.Fatal("asking twice gave two compartments")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := search.Acquire(context.Background()); err != nil {
+			t.Fatalf("search call %d: %v", i+1, err)
+		}
+	}
+	_, err := search.Acquire(context.Background())
+	fullError(t, err, "search", false)
+
+	payments := b.Compartment("payments") // has no configuration of its own
+	if _, err := payments.Acquire(context.Background()); err != nil {
+		t.Fatalf("payments: %v, want a full search compartment not to get in the way", err)
+	}
+	_, err = payments.Acquire(context.Background())
+	fullError(t, err, "payments", false)
+}
@@ -0,0 +1,112 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+/**
+Not every error returned by an upstream means the upstream is in trouble.  A 404 or a validation error is the caller's
+problem, retrying it will only produce the same answer and counting it against a circuit breaker can trip the circuit
+for everybody.  A Classifier looks at an error and decides which Class it belongs to so the stability patterns can
+treat it accordingly.
+*/
+
+type Class int
+
+const (
+	Failure   Class = iota // The upstream is at fault: counted by a circuit breaker and retried.  The default.
+	Retryable              // Transient but not a fault, eg. a conflict: retried but not counted by a circuit breaker.
+	Permanent              // Retrying won't help and the upstream is fine, eg. a 404: neither counted nor retried.
+)
+
+func (c Class) String() string {
+	switch c {
+	case Failure:
+		return "failure"
+	case Retryable:
+		return "retryable"
+	case Permanent:
+		return "permanent"
+	}
+	return "unknown"
+}
+
+// CountsAsFailure reports whether a circuit breaker should count errors of this class.
+func (c Class) CountsAsFailure() bool { return c == Failure }
+
+// ShouldRetry reports whether a retry should try again after an error of this class.
+func (c Class) ShouldRetry() bool { return c != Permanent }
+
+// A Classifier returns the class of err, or false if it has no opinion about it so the next classifier gets a go.
+type Classifier func(err error) (Class, bool)
+
+// Is classifies errors matching target with errors.Is, so wrapped sentinel errors are recognised too.
+func Is(target error, class Class) Classifier {
+	return func(err error) (Class, bool) {
+		if errors.Is(err, target) {
+			return class, true
+		}
+		return 0, false
+	}
+}
+
+// As classifies errors that errors.As can find in the chain.  target is a nil pointer of the wanted error type, for
+// example (*net.OpError)(nil), or a pointer to the wanted interface, for example (*interface{ Timeout() bool })(nil).
+func As(target interface{}, class Class) Classifier {
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		panic("resilience: As target must be a pointer")
+	}
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	switch elem := typ.Elem(); {
+	case elem.Kind() == reflect.Interface || elem.Implements(errorType): // points at the wanted type
+		typ = elem
+	case typ.Implements(errorType): // is the wanted type
+	default:
+		panic("resilience: As target must be an error type or a pointer to an interface")
+	}
+	return func(err error) (Class, bool) {
+		if errors.As(err, reflect.New(typ).Interface()) { // a fresh target per call keeps it goroutine safe
+			return class, true
+		}
+		return 0, false
+	}
+}
+
+// Chain asks each classifier in turn and returns the first answer.
+func Chain(classifiers ...Classifier) Classifier {
+	return func(err error) (Class, bool) {
+		for _, c := range classifiers {
+			if c == nil {
+				continue
+			}
+			if class, ok := c(err); ok {
+				return class, true
+			}
+		}
+		return 0, false
+	}
+}
+
+var (
+	// Canceled means the caller gave up, which says nothing about the upstream.
+	Canceled = Is(context.Canceled, Permanent)
+	// DeadlineExceeded means the upstream was too slow to answer in time.
+	DeadlineExceeded = Is(context.DeadlineExceeded, Failure)
+)
+
+// DefaultClassifier knows about context cancellation and deadlines.
+func DefaultClassifier() Classifier {
+	return Chain(Canceled, DeadlineExceeded)
+}
+
+// Classify returns the class c gives err, falling back to the default classifier and then to Failure.  A nil error
+// has no class and Classify should not be called with it.
+func Classify(err error, c Classifier) Class {
+	if class, ok := Chain(c, DefaultClassifier())(err); ok {
+		return class
+	}
+	return Failure
+}
@@ -0,0 +1,145 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type statusError struct{ code int }
+
+func (e *statusError) Error() string { return http.StatusText(e.code) }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestClassify(t *testing.T) {
+	classifier := Chain(
+		nil, // skipped
+		Is(errNotFound, Permanent),
+		As((*statusError)(nil), Retryable),
+		As((*interface{ Timeout() bool })(nil), Failure),
+		Is(errUpstream, Permanent), // never reached for an error the classifiers above claimed
+	)
+	tests := []struct {
+		name string
+		err  error
+		want Class
+	}{
+		{"sentinel", errNotFound, Permanent},
+		{"wrapped sentinel", fmt.Errorf("get user: %w", errNotFound), Permanent},
+		{"type", &statusError{http.StatusConflict}, Retryable},
+		{"wrapped type", fmt.Errorf("put user: %w", &statusError{http.StatusConflict}), Retryable},
+		{"interface", fmt.Errorf("read: %w", timeoutError{}), Failure},
+		{"first answer wins", fmt.Errorf("%w: %w", &statusError{http.StatusConflict}, errUpstream), Retryable},
+		{"later classifier", errUpstream, Permanent},
+		{"cancelled", fmt.Errorf("call: %w", context.Canceled), Permanent},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), Failure},
+		{"unknown", errors.New("something else"), Failure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err, classifier); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultClasses(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Class
+	}{
+		{context.Canceled, Permanent},
+		{context.DeadlineExceeded, Failure},
+		{errUpstream, Failure},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err, nil); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClassifierOverridesDefault(t *testing.T) {
+	if got := Classify(context.DeadlineExceeded, Is(context.DeadlineExceeded, Permanent)); got != Permanent {
+		t.Fatalf("got %v, want the caller's classifier to be asked before the default", got)
+	}
+}
+
+func TestChainWithoutAnswer(t *testing.T) {
+	if _, ok := Chain(nil, Is(errNotFound, Permanent))(errUpstream); ok {
+		t.Fatal("got an answer for an error no classifier knows")
+	}
+}
+
+func TestAsPanicsOnNonPointer(t *testing.T) {
+	for _, target := range []interface{}{nil, statusError{}, (*int)(nil)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("As(%T) did not panic", target)
+				}
+			}()
+			As(target, Failure)
+		}()
+	}
+}
+
+func TestClassBehaviour(t *testing.T) {
+	tests := []struct {
+		class           Class
+		name            string
+		countsAsFailure bool
+		shouldRetry     bool
+	}{
+		{Failure, "failure", true, true},
+		{Retryable, "retryable", false, true},
+		{Permanent, "permanent", false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.class.String(); got != tt.name {
+			t.Errorf("String() = %q, want %q", got, tt.name)
+		}
+		if got := tt.class.CountsAsFailure(); got != tt.countsAsFailure {
+			t.Errorf("%v: CountsAsFailure() = %v, want %v", tt.class, got, tt.countsAsFailure)
+		}
+		if got := tt.class.ShouldRetry(); got != tt.shouldRetry {
+			t.Errorf("%v: ShouldRetry() = %v, want %v", tt.class, got, tt.shouldRetry)
+		}
+	}
+}
+
+func TestRetryClassifierStopsOnPermanent(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{"permanent", fmt.Errorf("get user: %w", errNotFound), 1},
+		{"failure", errUpstream, 3},
+		{"cancelled", context.Canceled, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			r := Retry(func(ctx context.Context) (string, error) {
+				calls++
+				return "", tt.err
+			}, 2, time.Millisecond, WithRetryClassifier(Is(errNotFound, Permanent)))
+
+			if _, err := r(context.Background()); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("made %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
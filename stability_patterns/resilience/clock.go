@@ -0,0 +1,96 @@
+package resilience
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the rate limiters.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type limiterConfig struct {
+	clock Clock
+}
+
+type LimiterOption func(*limiterConfig)
+
+// WithClock makes a limiter read the time from c instead of the system clock.
+func WithClock(c Clock) LimiterOption {
+	return func(config *limiterConfig) {
+		if c != nil {
+			config.clock = c
+		}
+	}
+}
+
+func newLimiterConfig(opts []LimiterOption) limiterConfig {
+	config := limiterConfig{clock: realClock{}}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// ManualClock only moves when it is told to, so tests can drive the limiters through hours of traffic without
+// sleeping.
+type ManualClock struct {
+	m       sync.Mutex
+	now     time.Time
+	waiters []manualWaiter
+}
+
+type manualWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+// After fires once the clock has been advanced by d.
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, manualWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d and fires everything waiting until then.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = c.now.Add(d)
+
+	sort.Slice(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+	fired := 0
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			break
+		}
+		w.c <- c.now
+		fired++
+	}
+	c.waiters = c.waiters[fired:]
+}
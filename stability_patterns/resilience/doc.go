@@ -0,0 +1,25 @@
+// Package resilience holds the stability patterns.  Each one wraps an Effector, the function that talks to an upstream
+// service, and returns a function with exactly the same signature, so they can be stacked on top of each other in
+// whatever order makes sense.
+//
+// Protecting the upstream:
+//   - Breaker stops calling an upstream that keeps failing and probes it again after a while, see NewCircuitBreaker.
+//   - Throttle and ThrottleWith limit the rate of calls with a Limiter: a TokenBucket, a SlidingWindowLog, a
+//     SlidingWindowCounter, a GCRA or a MultiLimiter enforcing several quotas at once.  ThrottleByKey gives every key,
+//     eg. every client, a bucket of its own.
+//   - Adaptive limits the calls in flight to what an AdaptiveLimiter learns the upstream can take, see AIMD, Vegas and
+//     Gradient.
+//   - Isolate and Bulkhead cap the calls in flight to each upstream, so a slow one can't hold up the others, see
+//     NewBulkheads.
+//   - Shed turns calls away, lowest Priority first, once a Shedder is overloaded.
+//
+// Coping with failures:
+//   - Retry tries again with a Backoff, optionally limited by a RetryBudget and watched by a RetryObserver.
+//   - Timeout gives up on calls that take too long.
+//   - Hedge sends another copy of a slow call and takes whichever answers first.
+//   - Fallback answers with an alternative, a cached or a default value instead of an error.
+//
+// A Classifier decides which errors count as failures and which are worth retrying.  A Policy puts the layers together
+// in an order that works and tells which layer turned a call down.  Transport, ThrottleHandler and ShedHandler apply
+// the patterns to HTTP clients and servers.
+package resilience
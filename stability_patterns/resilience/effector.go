@@ -0,0 +1,14 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+)
+
+// Effector is the signature of the function interacting with your upstream service.  T is whatever the upstream
+// returns: a struct, a []byte, or struct{} when there is nothing to return.
+type Effector[T any] func(context.Context) (T, error)
+
+// errPanicked is the outcome a layer records for a call into an effector that panicked, so the panic doesn't leave the
+// layer's bookkeeping behind before it carries on up the stack.
+var errPanicked = errors.New("effector panicked")
@@ -0,0 +1,170 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/**
+When the circuit is open or the retries have run out, most callers do the same thing: ask a secondary upstream, serve
+the last answer that worked or, failing both, return a sensible default.  Fallback does that for them.  It tries, in
+this order, an alternative effector, the cached last good value as long as it isn't too old, and a static default.
+
+The answer is wrapped in a Result telling where it came from, so callers and metrics can tell a degraded answer from a
+real one.  Only errors of the selected classes fall back, by default Failure and Retryable: a Permanent error, such as
+a 404 or a cancelled context, is the caller's problem and is returned as it is.
+
+Policy.Fallback is deliberately simpler.  Every layer of a policy must return the same T as the effector it wraps, so
+it can't answer with a Result, and it hands the error to a function that decides what to do with it.  To have both,
+build the policy without a fallback and wrap what Build returns in Fallback.
+*/
+
+type Source int
+
+const (
+	Primary     Source = iota // the wrapped effector answered
+	Alternative               // the alternative effector answered
+	Cached                    // the last good answer of the wrapped effector
+	Default                   // the static default
+)
+
+func (s Source) String() string {
+	switch s {
+	case Primary:
+		return "primary"
+	case Alternative:
+		return "alternative"
+	case Cached:
+		return "cached"
+	case Default:
+		return "default"
+	}
+	return "unknown"
+}
+
+// Result is what a Fallback returns.  Err is the error of the wrapped effector when the answer is degraded.
+type Result[T any] struct {
+	Value  T
+	Source Source
+	Age    time.Duration // how old a cached value is
+	Err    error
+}
+
+// Degraded reports whether the answer didn't come from the wrapped effector.
+func (r Result[T]) Degraded() bool { return r.Source != Primary }
+
+type fallbackConfig[T any] struct {
+	classifier  Classifier
+	classes     map[Class]bool
+	alternative Effector[T]
+	maxAge      time.Duration
+	cache       bool
+	value       T
+	hasDefault  bool
+	clock       Clock
+}
+
+type FallbackOption[T any] func(*fallbackConfig[T])
+
+// WithAlternative asks e when the wrapped effector fails.
+func WithAlternative[T any](e Effector[T]) FallbackOption[T] {
+	return func(c *fallbackConfig[T]) {
+		c.alternative = e
+	}
+}
+
+// WithCachedValue keeps the last good answer and serves it for up to maxAge after it was fetched.  A maxAge of zero
+// serves it however old it is.
+func WithCachedValue[T any](maxAge time.Duration) FallbackOption[T] {
+	return func(c *fallbackConfig[T]) {
+		c.cache = true
+		c.maxAge = maxAge
+	}
+}
+
+// WithDefault returns value when nothing else is available.
+func WithDefault[T any](value T) FallbackOption[T] {
+	return func(c *fallbackConfig[T]) {
+		c.value = value
+		c.hasDefault = true
+	}
+}
+
+// WithFallbackOn replaces the classes of errors that fall back.
+func WithFallbackOn[T any](classes ...Class) FallbackOption[T] {
+	return func(c *fallbackConfig[T]) {
+		c.classes = make(map[Class]bool, len(classes))
+		for _, class := range classes {
+			c.classes[class] = true
+		}
+	}
+}
+
+// WithFallbackClassifier decides the class of an error.  The default classifier is consulted after it.
+func WithFallbackClassifier[T any](classifiers ...Classifier) FallbackOption[T] {
+	return func(c *fallbackConfig[T]) {
+		c.classifier = Chain(classifiers...)
+	}
+}
+
+// WithFallbackClock replaces the clock used to age the cached value.
+func WithFallbackClock[T any](clock Clock) FallbackOption[T] {
+	return func(c *fallbackConfig[T]) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// Fallback calls e and, when it fails with an error of the selected classes, falls back on the alternative effector,
+// the cached value and the default, in that order.  When none of them can answer the error of e is returned.
+func Fallback[T any](e Effector[T], opts ...FallbackOption[T]) Effector[Result[T]] {
+	config := fallbackConfig[T]{
+		classes: map[Class]bool{Failure: true, Retryable: true},
+		clock:   realClock{},
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var (
+		mu      sync.Mutex
+		last    T
+		fetched time.Time
+	)
+
+	return func(ctx context.Context) (Result[T], error) {
+		response, err := e(ctx)
+		if err == nil {
+			if config.cache {
+				mu.Lock()
+				last, fetched = response, config.clock.Now()
+				mu.Unlock()
+			}
+			return Result[T]{Value: response, Source: Primary}, nil
+		}
+		if !config.classes[Classify(err, config.classifier)] {
+			return Result[T]{Value: response, Source: Primary}, err
+		}
+
+		if config.alternative != nil {
+			if value, altErr := config.alternative(ctx); altErr == nil {
+				return Result[T]{Value: value, Source: Alternative, Err: err}, nil
+			}
+		}
+		if config.cache {
+			mu.Lock()
+			value, at := last, fetched
+			mu.Unlock()
+			age := config.clock.Now().Sub(at)
+			if !at.IsZero() && (config.maxAge == 0 || age <= config.maxAge) {
+				return Result[T]{Value: value, Source: Cached, Age: age, Err: err}, nil
+			}
+		}
+		if config.hasDefault {
+			return Result[T]{Value: config.value, Source: Default, Err: err}, nil
+		}
+		return Result[T]{Value: response, Source: Primary}, err
+	}
+}
@@ -0,0 +1,163 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("404 Not found")
+
+// switchable answers value, or fails with err once it is set.
+type switchable struct {
+	value string
+	err   error
+}
+
+func (s *switchable) call(ctx context.Context) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.value, nil
+}
+
+func TestFallbackOrder(t *testing.T) {
+	failingAlternative := WithAlternative(func(ctx context.Context) (string, error) { return "", errUpstream })
+	tests := []struct {
+		name   string
+		opts   []FallbackOption[string]
+		cached bool // whether the primary answered once before failing
+		want   Result[string]
+	}{
+		{"alternative first", []FallbackOption[string]{
+			WithAlternative(func(ctx context.Context) (string, error) { return "secondary", nil }),
+			WithCachedValue[string](0),
+			WithDefault("default"),
+		}, true, Result[string]{Value: "secondary", Source: Alternative}},
+		{"cached when the alternative fails", []FallbackOption[string]{
+			failingAlternative, WithCachedValue[string](0), WithDefault("default"),
+		}, true, Result[string]{Value: "fresh", Source: Cached}},
+		{"default when nothing is cached", []FallbackOption[string]{
+			failingAlternative, WithCachedValue[string](0), WithDefault("default"),
+		}, false, Result[string]{Value: "default", Source: Default}},
+		{"default without a cache", []FallbackOption[string]{
+			WithDefault("default"),
+		}, true, Result[string]{Value: "default", Source: Default}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &switchable{value: "fresh"}
+			f := Fallback(upstream.call, tt.opts...)
+			if tt.cached {
+				if r, err := f(context.Background()); err != nil || r.Value != "fresh" || r.Degraded() {
+					t.Fatalf("got %+v, %v, want the primary's answer", r, err)
+				}
+			}
+
+			upstream.err = errUpstream
+			r, err := f(context.Background())
+			if err != nil {
+				t.Fatalf("got %v, want a fallback answer", err)
+			}
+			if r.Value != tt.want.Value || r.Source != tt.want.Source || !r.Degraded() || !errors.Is(r.Err, errUpstream) {
+				t.Fatalf("got %+v, want %v from %v, degraded and carrying the primary's error", r, tt.want.Value, tt.want.Source)
+			}
+		})
+	}
+}
+
+func TestFallbackWithNothingToFallBackOn(t *testing.T) {
+	f := Fallback((&switchable{err: errUpstream}).call,
+		WithAlternative(func(ctx context.Context) (string, error) { return "", errors.New("also down") }))
+	r, err := f(context.Background())
+	if !errors.Is(err, errUpstream) || r.Degraded() {
+		t.Fatalf("got %+v, %v, want the primary's error", r, err)
+	}
+}
+
+func TestFallbackClasses(t *testing.T) {
+	errConflict := errors.New("409 Conflict")
+	classifier := WithFallbackClassifier[string](Is(errNotFound, Permanent), Is(errConflict, Retryable))
+	tests := []struct {
+		name     string
+		opts     []FallbackOption[string]
+		err      error
+		fallback bool
+	}{
+		{"failure", nil, errUpstream, true},
+		{"retryable", nil, errConflict, true},
+		{"permanent", nil, errNotFound, false},
+		{"cancelled", nil, context.Canceled, false},
+		{"only failures", []FallbackOption[string]{WithFallbackOn[string](Failure)}, errConflict, false},
+		{"permanent selected", []FallbackOption[string]{WithFallbackOn[string](Permanent)}, errNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]FallbackOption[string]{classifier, WithDefault("default")}, tt.opts...)
+			r, err := Fallback((&switchable{err: tt.err}).call, opts...)(context.Background())
+			if tt.fallback {
+				if err != nil || r.Source != Default {
+					t.Fatalf("got %+v, %v, want the default", r, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) || r.Degraded() {
+				t.Fatalf("got %+v, %v, want %v returned as it is", r, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFallbackCacheStaleness(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	upstream := &switchable{value: "fresh"}
+	f := Fallback(upstream.call, WithCachedValue[string](time.Minute), WithDefault("default"),
+		WithFallbackClock[string](clock))
+
+	f(context.Background())
+	upstream.err = errUpstream
+
+	clock.Advance(time.Minute)
+	r, _ := f(context.Background())
+	if r.Source != Cached || r.Value != "fresh" || r.Age != time.Minute {
+		t.Fatalf("got %+v, want the cached value a minute old", r)
+	}
+
+	clock.Advance(time.Nanosecond)
+	if r, _ := f(context.Background()); r.Source != Default {
+		t.Fatalf("got %+v, want the default once the cached value is too old", r)
+	}
+
+	upstream.err = nil
+	upstream.value = "fresher"
+	f(context.Background()) // refreshes the cache
+	upstream.err = errUpstream
+	clock.Advance(30 * time.Second)
+	if r, _ := f(context.Background()); r.Value != "fresher" || r.Age != 30*time.Second {
+		t.Fatalf("got %+v, want the newer value 30s old", r)
+	}
+}
+
+func TestResultDegraded(t *testing.T) {
+	for source, want := range map[Source]bool{Primary: false, Alternative: true, Cached: true, Default: true} {
+		if got := (Result[string]{Source: source}).Degraded(); got != want {
+			t.Errorf("%v: Degraded() = %v, want %v", source, got, want)
+		}
+	}
+}
+
+func TestFallbackAroundPolicy(t *testing.T) {
+	upstream := &switchable{value: "fresh"}
+	call, err := NewPolicy[string]().Retry(1, 0).Build(upstream.call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := Fallback(call, WithDefault("default"))
+
+	upstream.err = errUpstream
+	r, err := f(context.Background())
+	if err != nil || r.Source != Default || !errors.Is(r.Err, ErrRetriesExhausted) {
+		t.Fatalf("got %+v, %v, want the default with the policy's error", r, err)
+	}
+}
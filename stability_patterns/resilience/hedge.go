@@ -0,0 +1,151 @@
+package resilience
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+/**
+Hedged requests, the replicated requests idea from Concurrency in Go, trade a little extra load for a shorter tail: if
+the first call has not answered within a delay a second copy is sent, and whichever answers first wins.  The losers
+are cancelled through their context, so only use it for idempotent calls that respect ctx.
+
+The delay is either fixed or a percentile of the recently observed latencies, so that only the slowest calls get
+hedged.
+*/
+
+type hedgeConfig struct {
+	percentile float64
+	samples    int
+}
+
+type HedgeOption func(*hedgeConfig)
+
+// WithHedgePercentile hedges calls that take longer than the p-th percentile (0-100) of the last samples successful
+// calls.  The fixed delay is used until that many calls have been seen.
+func WithHedgePercentile(p float64, samples int) HedgeOption {
+	return func(c *hedgeConfig) {
+		if p > 0 && p <= 100 && samples > 0 {
+			c.percentile = p
+			c.samples = samples
+		}
+	}
+}
+
+// Hedge calls e and sends another copy every delay while none has answered, up to copies calls in flight.  A failed
+// copy is replaced straight away.  It returns the first success, or the last error once every copy has failed.
+func Hedge[T any](e Effector[T], copies int, delay time.Duration, opts ...HedgeOption) Effector[T] {
+	var config hedgeConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if copies < 1 {
+		copies = 1
+	}
+	latencies := newLatencies(config.samples)
+
+	return func(parent context.Context) (T, error) {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel() // cancels the copies that lost
+
+		type result struct {
+			response T
+			err      error
+			took     time.Duration
+		}
+		results := make(chan result, copies) // buffered so the losers never block
+
+		launched, finished := 0, 0
+		var timer *time.Timer
+		var hedge <-chan time.Time
+		launch := func() {
+			launched++
+			go func() {
+				start := time.Now()
+				response, err := e(ctx)
+				results <- result{response, err, time.Since(start)}
+			}()
+
+			if timer != nil {
+				timer.Stop()
+			}
+			hedge = nil
+			if launched < copies {
+				timer = time.NewTimer(latencies.delay(config.percentile, delay))
+				hedge = timer.C
+			}
+		}
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		launch()
+		var zero T
+		var lastErr error
+		for {
+			select {
+			case r := <-results:
+				finished++
+				if r.err == nil {
+					latencies.observe(r.took)
+					return r.response, nil
+				}
+				lastErr = r.err
+				if launched < copies && ctx.Err() == nil {
+					launch()
+				} else if finished == launched {
+					return zero, lastErr
+				}
+			case <-hedge:
+				launch()
+			case <-parent.Done():
+				return zero, parent.Err()
+			}
+		}
+	}
+}
+
+// latencies keeps the durations of the last len(samples) successful calls in a ring buffer.
+type latencies struct {
+	m       sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func newLatencies(n int) *latencies {
+	return &latencies{samples: make([]time.Duration, n)}
+}
+
+func (l *latencies) observe(d time.Duration) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if len(l.samples) == 0 {
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % len(l.samples)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// delay returns the p-th percentile of the samples, or fallback until the ring buffer has filled up.
+func (l *latencies) delay(p float64, fallback time.Duration) time.Duration {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if p == 0 || !l.full {
+		return fallback
+	}
+	sorted := append([]time.Duration(nil), l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(p/100*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,168 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeDoesNotHedgeFastCalls(t *testing.T) {
+	var calls atomic.Int32
+	h := Hedge(func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "ok", nil
+	}, 3, 50*time.Millisecond)
+
+	if got, err := h(context.Background()); err != nil || got != "ok" {
+		t.Fatalf("got %q, %v, want ok", got, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("made %d calls, want 1", n)
+	}
+}
+
+func TestHedgeCancelsTheLosers(t *testing.T) {
+	var calls atomic.Int32
+	cancelled := make(chan struct{})
+	h := Hedge(func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done() // the first copy hangs until it loses
+			close(cancelled)
+			return "", ctx.Err()
+		}
+		return "hedged", nil
+	}, 2, 10*time.Millisecond)
+
+	start := time.Now()
+	if got, err := h(context.Background()); err != nil || got != "hedged" {
+		t.Fatalf("got %q, %v, want hedged", got, err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("took %v, want the second copy to answer after the delay", took)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the losing copy was not cancelled")
+	}
+}
+
+func TestHedgeReplacesFailedCopies(t *testing.T) {
+	var calls atomic.Int32
+	h := Hedge(func(ctx context.Context) (string, error) {
+		if calls.Add(1) < 3 {
+			return "", errUpstream
+		}
+		return "ok", nil
+	}, 3, time.Hour)
+
+	if got, err := h(context.Background()); err != nil || got != "ok" {
+		t.Fatalf("got %q, %v, want ok without waiting for the delay", got, err)
+	}
+}
+
+func TestHedgeReturnsLastErrorWhenEveryCopyFails(t *testing.T) {
+	var calls atomic.Int32
+	h := Hedge(func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "", errUpstream
+	}, 3, time.Hour)
+
+	if _, err := h(context.Background()); !errors.Is(err, errUpstream) {
+		t.Fatalf("got %v, want %v", err, errUpstream)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("made %d calls, want 3", n)
+	}
+}
+
+func TestHedgeStopsWhenCallerGivesUp(t *testing.T) {
+	h := Hedge(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, 3, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := h(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLatencies(t *testing.T) {
+	l := newLatencies(4)
+	for _, d := range []time.Duration{40, 10, 30} {
+		l.observe(d * time.Millisecond)
+	}
+	if got := l.delay(50, time.Second); got != time.Second {
+		t.Fatalf("got %v, want the fallback until the samples fill up", got)
+	}
+	l.observe(20 * time.Millisecond)
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{50, 20 * time.Millisecond},
+		{75, 30 * time.Millisecond},
+		{100, 40 * time.Millisecond},
+		{0, time.Second}, // no percentile, the fixed delay
+	}
+	for _, tt := range tests {
+		if got := l.delay(tt.p, time.Second); got != tt.want {
+			t.Errorf("p%v: got %v, want %v", tt.p, got, tt.want)
+		}
+	}
+
+	l.observe(50 * time.Millisecond) // replaces the oldest sample, 40ms
+	if got := l.delay(100, time.Second); got != 50*time.Millisecond {
+		t.Errorf("after a new sample: got %v, want %v", got, 50*time.Millisecond)
+	}
+	if got := l.delay(75, time.Second); got != 30*time.Millisecond {
+		t.Errorf("after a new sample: got %v, want %v", got, 30*time.Millisecond)
+	}
+}
+
+func TestHedgePercentileDelay(t *testing.T) {
+	var calls atomic.Int32
+	slow := make(chan struct{})
+	h := Hedge(func(ctx context.Context) (string, error) {
+		n := calls.Add(1)
+		if n <= 5 {
+			return "ok", nil // fast calls to fill the samples
+		}
+		if n == 6 {
+			select {
+			case <-slow:
+			case <-ctx.Done():
+			}
+			return "", ctx.Err()
+		}
+		return "hedged", nil
+	}, 2, time.Hour, WithHedgePercentile(90, 5))
+
+	for i := 0; i < 5; i++ {
+		if _, err := h(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer close(slow)
+
+	done := make(chan string, 1)
+	go func() {
+		got, _ := h(context.Background())
+		done <- got
+	}()
+	select {
+	case got := <-done:
+		if got != "hedged" {
+			t.Fatalf("got %q, want hedged", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("still waiting for the fixed delay, want the percentile of the fast calls")
+	}
+}
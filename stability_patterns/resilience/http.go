@@ -0,0 +1,248 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+Most effectors wrapped by this package make an HTTP call.  Transport applies a Policy to every request an http.Client
+sends, with a breaker, throttle and so on of its own for every host, so one struggling host doesn't trip the circuit
+for the others.
+
+A request is only retried when doing it twice is safe: its method must be idempotent, or it must carry an
+Idempotency-Key header, and its body must be rewindable through GetBody, which http.NewRequest sets up for the usual
+body types.  Responses with a 429 or 5xx status count as failures and their Retry-After header is honoured.  When the
+retries run out the last response is returned as it is, as an http.Client expects, and the bodies of the earlier ones
+are closed.
+
+On the server side ThrottleHandler and ShedHandler turn away requests with 429 Too Many Requests and 503 Service
+Unavailable, telling the client when to come back with a Retry-After header.
+*/
+
+// StatusError is the error an attempt fails with when the upstream answers with a 429 or 5xx status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Delay      time.Duration // from the Retry-After header, if any
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %v", e.Status)
+}
+
+func (e *StatusError) RetryAfter() time.Duration { return e.Delay }
+
+var errNoResponse = errors.New("policy returned neither a response nor an error")
+
+type Transport struct {
+	base   http.RoundTripper
+	policy *Policy[*http.Response]
+
+	m     sync.Mutex
+	hosts map[string]Effector[*http.Response]
+}
+
+// NewTransport sends requests through base, http.DefaultTransport if nil, wrapped in policy.  The policy is built
+// once per host.
+func NewTransport(base http.RoundTripper, policy *Policy[*http.Response]) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, policy: policy, hosts: make(map[string]Effector[*http.Response])}
+}
+
+type roundTripKey struct{}
+
+// roundTrip is what the effector of a host needs to know about the request it sends.
+type roundTrip struct {
+	req *http.Request
+
+	m        sync.Mutex // an attempt abandoned by a Timeout may still be running
+	attempts int
+	last     *http.Response
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	effector, err := t.host(req.URL.Host)
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	rt := &roundTrip{req: req}
+	ctx := context.WithValue(req.Context(), roundTripKey{}, rt)
+	if !replayable(req) {
+		ctx = withoutRetries(ctx)
+	}
+	resp, err := effector(ctx)
+
+	rt.m.Lock()
+	defer rt.m.Unlock()
+	if rt.attempts == 0 {
+		closeBody(req) // a layer turned the request away or answered it, base never saw the body
+	}
+	var status *StatusError
+	if err != nil && resp != nil && resp == rt.last && errors.As(err, &status) {
+		return resp, nil // a failed status is still an answer, the caller gets to see it
+	}
+	if rt.last != nil && rt.last != resp {
+		rt.last.Body.Close()
+	}
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errNoResponse
+	}
+	return resp, nil // from the upstream, or made up by a layer such as a fallback
+}
+
+func (t *Transport) host(host string) (Effector[*http.Response], error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if e, ok := t.hosts[host]; ok {
+		return e, nil
+	}
+	e, err := t.policy.Build(t.send)
+	if err != nil {
+		return nil, err
+	}
+	t.hosts[host] = e
+	return e, nil
+}
+
+// send makes one attempt at the request found in ctx.
+func (t *Transport) send(ctx context.Context) (*http.Response, error) {
+	rt := ctx.Value(roundTripKey{}).(*roundTrip)
+	rt.m.Lock()
+	if rt.last != nil {
+		rt.last.Body.Close() // nobody is going to read the previous answer
+		rt.last = nil
+	}
+	rt.attempts++
+	attempt := rt.attempts
+	rt.m.Unlock()
+
+	req := rt.req
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+
+	// ctx only limits the wait for the response headers, the body must stay readable once the attempt is over.
+	reqCtx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(ctx, cancel)
+	resp, err := t.base.RoundTrip(req.WithContext(reqCtx))
+	if !stop() || err != nil {
+		cancel()
+		if err == nil {
+			resp.Body.Close() // the attempt was abandoned just as the answer arrived
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	rt.m.Lock()
+	defer rt.m.Unlock()
+	if ctx.Err() != nil {
+		resp.Body.Close() // the attempt was abandoned while the answer was on its way
+		return nil, ctx.Err()
+	}
+	rt.last = resp
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		delay, _ := ResponseRetryAfter(resp)
+		return resp, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Delay: delay}
+	}
+	return resp, nil
+}
+
+// cancelBody releases the context of a request once its body has been closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// replayable reports whether req can safely be sent more than once.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" && req.Header.Get("X-Idempotency-Key") == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// closeBody closes the body of a request that is never sent, as a RoundTripper must.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// ThrottleHandler answers 429 Too Many Requests when l doesn't let a request through.
+func ThrottleHandler(next http.Handler, l Limiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := l.Take(); err != nil {
+			delay, _ := retryAfter(err)
+			reject(w, http.StatusTooManyRequests, delay)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ShedHandler answers 503 Service Unavailable when s sheds a request, asking the client to come back after
+// retryAfter.  The priority of a request is taken from its context, see WithPriority.
+func ShedHandler(next http.Handler, s *Shedder, retryAfter time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, err := s.Acquire(r.Context())
+		if err != nil {
+			if r.Context().Err() != nil {
+				return // the client is gone
+			}
+			reject(w, http.StatusServiceUnavailable, retryAfter)
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reject answers with status and a Retry-After header rounded up to whole seconds, leaving the header out when the
+// delay is unknown or unbounded.
+func reject(w http.ResponseWriter, status int, delay time.Duration) {
+	if delay > 0 && delay < math.MaxInt64 { // a limiter that never lets anything through has no time to give
+		seconds := int64(delay / time.Second)
+		if delay%time.Second != 0 {
+			seconds++
+		}
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	http.Error(w, http.StatusText(status), status)
+}
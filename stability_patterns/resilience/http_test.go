@@ -0,0 +1,248 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flaky answers 503 to the first failures requests and echoes the method and body after that.
+func flaky(failures int32) (*httptest.Server, *int32) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) <= failures {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Method+" "+string(body))
+	}))
+	return server, &hits
+}
+
+func retryClient(policy *Policy[*http.Response]) *http.Client {
+	return &http.Client{Transport: NewTransport(nil, policy)}
+}
+
+// body reads the status and body of a response, failing the test on an error.
+func body(t *testing.T) func(*http.Response, error) (int, string) {
+	return func(resp *http.Response, err error) (int, string) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("reading the body: %v", err)
+		}
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+}
+
+func TestTransportRetriesIdempotentRequests(t *testing.T) {
+	server, hits := flaky(1)
+	defer server.Close()
+	client := retryClient(NewPolicy[*http.Response]().Retry(2, time.Millisecond).Timeout(time.Second))
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("update"))
+	status, got := body(t)(client.Do(req))
+	if status != http.StatusOK || got != "PUT update" {
+		t.Fatalf("got %d %q, want 200 with the body sent again", status, got)
+	}
+	if *hits != 2 {
+		t.Fatalf("%d requests reached the upstream, want 2", *hits)
+	}
+}
+
+func TestTransportDoesNotRetryPost(t *testing.T) {
+	server, hits := flaky(1)
+	defer server.Close()
+	client := retryClient(NewPolicy[*http.Response]().Retry(2, time.Millisecond))
+
+	status, got := body(t)(client.Post(server.URL, "text/plain", strings.NewReader("order")))
+	if status != http.StatusServiceUnavailable || got != "down" {
+		t.Fatalf("got %d %q, want the 503 itself", status, got)
+	}
+	if *hits != 1 {
+		t.Fatalf("%d requests reached the upstream, want 1", *hits)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("order"))
+	req.Header.Set("Idempotency-Key", "42")
+	if status, _ := body(t)(client.Do(req)); status != http.StatusOK {
+		t.Fatalf("got %d, want a POST with an idempotency key retried", status)
+	}
+}
+
+// trackingTransport remembers the bodies of the responses it hands out.
+type trackingTransport struct {
+	m      sync.Mutex
+	bodies []*trackedBody
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	closed int32
+}
+
+func (b *trackedBody) Close() error {
+	atomic.AddInt32(&b.closed, 1)
+	return b.ReadCloser.Close()
+}
+
+func (tt *trackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b := &trackedBody{ReadCloser: resp.Body}
+	resp.Body = b
+	tt.m.Lock()
+	tt.bodies = append(tt.bodies, b)
+	tt.m.Unlock()
+	return resp, nil
+}
+
+func TestTransportReturnsLastStatusAndClosesTheOthers(t *testing.T) {
+	server, hits := flaky(10)
+	defer server.Close()
+	tracking := &trackingTransport{}
+	client := &http.Client{Transport: NewTransport(tracking, NewPolicy[*http.Response]().Retry(2, time.Millisecond))}
+
+	status, got := body(t)(client.Get(server.URL))
+	if status != http.StatusServiceUnavailable || got != "down" {
+		t.Fatalf("got %d %q, want the last 503 with a readable body", status, got)
+	}
+	if *hits != 3 || len(tracking.bodies) != 3 {
+		t.Fatalf("%d requests and %d responses, want 3 of each", *hits, len(tracking.bodies))
+	}
+	for i, b := range tracking.bodies {
+		if atomic.LoadInt32(&b.closed) != 1 {
+			t.Fatalf("body %d closed %d times, want once", i+1, b.closed)
+		}
+	}
+}
+
+func TestTransportFallbackResponse(t *testing.T) {
+	server, _ := flaky(10)
+	defer server.Close()
+	policy := NewPolicy[*http.Response]().Fallback(func(ctx context.Context, err error) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Body:       io.NopCloser(strings.NewReader("cached")),
+			Header:     http.Header{},
+		}, nil
+	})
+
+	status, got := body(t)(retryClient(policy).Get(server.URL))
+	if status != http.StatusOK || got != "cached" {
+		t.Fatalf("got %d %q, want the fallback response", status, got)
+	}
+}
+
+func TestTransportBreakerPerHost(t *testing.T) {
+	down, downHits := flaky(100)
+	defer down.Close()
+	up, _ := flaky(0)
+	defer up.Close()
+	client := retryClient(NewPolicy[*http.Response]().Breaker(1, WithOpenBackoff(time.Hour)))
+
+	body(t)(client.Get(down.URL))
+	if _, err := client.Get(down.URL); !errors.Is(err, ErrOpen) {
+		t.Fatalf("got %v, want %v", err, ErrOpen)
+	}
+	if *downHits != 1 {
+		t.Fatalf("%d requests reached the failing host, want 1", *downHits)
+	}
+	if status, _ := body(t)(client.Get(up.URL)); status != http.StatusOK {
+		t.Fatalf("got %d from the other host, want 200", status)
+	}
+}
+
+func TestThrottleHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		limiter    Limiter
+		retryAfter string
+	}{
+		{"token bucket", NewTokenBucket(1, 1, 1500*time.Millisecond), "2"},
+		{"never allows", NewSlidingWindowLog(0, time.Second), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ThrottleHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), tt.limiter)
+			var last *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				last = httptest.NewRecorder()
+				handler.ServeHTTP(last, httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+			if last.Code != http.StatusTooManyRequests {
+				t.Fatalf("got %d, want 429", last.Code)
+			}
+			if got := last.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Fatalf("Retry-After %q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestShedHandler(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	s := NewShedder(1, WithShedderClock(clock))
+	release, _ := s.Acquire(context.Background())
+	defer release()
+
+	handler := ShedHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), s, 3*time.Second)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	queued(t, s, 1)
+	clock.Advance(time.Second)
+	<-done
+
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "3" {
+		t.Fatalf("got %d with Retry-After %q, want 503 with 3", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+// trackedRequestBody records whether the transport closed the body of a request.
+type trackedRequestBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *trackedRequestBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestTransportClosesBodyOfRejectedRequest(t *testing.T) {
+	down, downHits := flaky(100)
+	defer down.Close()
+	client := retryClient(NewPolicy[*http.Response]().Breaker(1, WithOpenBackoff(time.Hour)))
+	body(t)(client.Get(down.URL))
+
+	reqBody := &trackedRequestBody{Reader: strings.NewReader("update")}
+	req, _ := http.NewRequest(http.MethodPut, down.URL, reqBody)
+	if _, err := client.Do(req); !errors.Is(err, ErrOpen) {
+		t.Fatalf("got %v, want %v", err, ErrOpen)
+	}
+	if !reqBody.closed.Load() {
+		t.Fatal("the body of a request the breaker turned away was left open")
+	}
+	if *downHits != 1 {
+		t.Fatalf("%d requests reached the upstream, want 1", *downHits)
+	}
+}
@@ -0,0 +1,146 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/**
+One bucket for everybody lets a single noisy tenant use up the allowance of all the others.  KeyedLimiter gives every
+key, a tenant, an API key or a client IP, a bucket of its own.  Buckets are created the first time a key is seen and
+dropped once the key has been idle for ttl, so the number of buckets stays bounded by the number of recently active
+keys.  Idle keys are looked for at most once every ttl, so a key may linger for up to twice that.
+
+Dropping a bucket forgets how many tokens it had left, so only full buckets are dropped: a key that used up its
+tokens, or has callers queued in Wait for tokens still to come, keeps its bucket until the refills have caught up.
+*/
+
+type KeyedLimiter struct {
+	max    uint
+	refill uint
+	d      time.Duration
+	ttl    time.Duration
+	opts   []LimiterOption
+	clock  Clock
+
+	m         sync.Mutex
+	buckets   map[string]*keyedBucket
+	lastSweep time.Time
+}
+
+type keyedBucket struct {
+	bucket   *TokenBucket
+	lastUsed time.Time
+}
+
+// NewKeyedLimiter gives every key a bucket of max tokens getting refill tokens back every d.  Keys unused for ttl
+// are evicted once their bucket is full again.  A ttl of zero or less keeps them for as long as it takes to refill an empty bucket, or an hour if it
+// never refills.
+func NewKeyedLimiter(max uint, refill uint, d time.Duration, ttl time.Duration, opts ...LimiterOption) *KeyedLimiter {
+	clock := newLimiterConfig(opts).clock
+	if ttl <= 0 {
+		ttl = refillTime(max, refill, d)
+	}
+	return &KeyedLimiter{
+		max:       max,
+		refill:    refill,
+		d:         d,
+		ttl:       ttl,
+		opts:      opts,
+		clock:     clock,
+		buckets:   make(map[string]*keyedBucket),
+		lastSweep: clock.Now(),
+	}
+}
+
+// Allow takes a token from key's bucket if there is one, without waiting.
+func (k *KeyedLimiter) Allow(key string) bool {
+	return k.bucket(key).Allow()
+}
+
+// Take takes a token from key's bucket if there is one, otherwise it returns a *ThrottledError saying when the next
+// one is due.
+func (k *KeyedLimiter) Take(key string) error {
+	return k.bucket(key).Take()
+}
+
+// Wait takes a token from key's bucket, waiting for one until ctx is done.
+func (k *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return k.bucket(key).Wait(ctx)
+}
+
+// Len is the number of keys with a bucket.
+func (k *KeyedLimiter) Len() int {
+	k.m.Lock()
+	defer k.m.Unlock()
+	k.sweep(k.clock.Now())
+	return len(k.buckets)
+}
+
+func (k *KeyedLimiter) bucket(key string) *TokenBucket {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	now := k.clock.Now()
+	k.sweep(now)
+	b, ok := k.buckets[key]
+	if !ok {
+		b = &keyedBucket{bucket: NewTokenBucket(k.max, k.refill, k.d, k.opts...)}
+		k.buckets[key] = b
+	}
+	b.lastUsed = now
+	return b.bucket
+}
+
+// sweep drops the idle buckets that have filled up again, at most once every ttl so a busy limiter doesn't walk the
+// map on every call.  Must be called with the lock held.
+func (k *KeyedLimiter) sweep(now time.Time) {
+	if now.Sub(k.lastSweep) < k.ttl {
+		return
+	}
+	k.lastSweep = now
+	for key, b := range k.buckets {
+		if now.Sub(b.lastUsed) >= k.ttl && b.bucket.full() {
+			delete(k.buckets, key)
+		}
+	}
+}
+
+// refillTime is how long an empty bucket takes to fill up again.
+func refillTime(max uint, refill uint, d time.Duration) time.Duration {
+	if refill == 0 || d <= 0 {
+		return time.Hour
+	}
+	refills := (max + refill - 1) / refill
+	if refills == 0 {
+		refills = 1
+	}
+	return time.Duration(refills) * d
+}
+
+// ThrottleByKey throttles e per key, taking the key for every call from its context.
+func ThrottleByKey[T any](e Effector[T], l *KeyedLimiter, key func(context.Context) string, opts ...ThrottleOption) Effector[T] {
+	var config throttleConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(ctx context.Context) (T, error) {
+		var zero T
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+
+		k := key(ctx)
+		if config.wait {
+			if err := l.Wait(ctx, k); err != nil {
+				return zero, err
+			}
+		} else if err := l.Take(k); err != nil {
+			return zero, err
+		}
+
+		return e(ctx)
+	}
+}
@@ -0,0 +1,87 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiterWithoutTTLStillThrottles(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	l := NewKeyedLimiter(1, 1, time.Hour, 0, WithClock(clock))
+
+	if !l.Allow("a") {
+		t.Fatal("first call was throttled")
+	}
+	for i := 0; i < 2; i++ {
+		if l.Allow("a") {
+			t.Fatalf("call %d was let through by an empty bucket", i+2)
+		}
+	}
+	clock.Advance(time.Hour)
+	if !l.Allow("a") {
+		t.Fatal("call after the refill was throttled")
+	}
+}
+
+func TestKeyedLimiterEvictsIdleKeys(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	l := NewKeyedLimiter(1, 1, time.Second, time.Minute, WithClock(clock))
+
+	l.Allow("a")
+	l.Allow("b")
+	if got := l.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+	clock.Advance(30 * time.Second)
+	l.Allow("b")
+	clock.Advance(40 * time.Second)
+	if got := l.Len(); got != 1 {
+		t.Fatalf("Len() = %d after a went idle, want 1", got)
+	}
+}
+
+func TestThrottleByKeyReportsDelay(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	l := NewKeyedLimiter(1, 1, time.Second, time.Minute, WithClock(clock))
+	e := ThrottleByKey(func(ctx context.Context) (string, error) { return "ok", nil }, l,
+		func(context.Context) string { return "tenant" })
+
+	if _, err := e(context.Background()); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	_, err := e(context.Background())
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter() != time.Second {
+		t.Fatalf("got %v, want a *ThrottledError asking to retry in 1s", err)
+	}
+}
+
+func TestKeyedLimiterKeepsBucketsWithQueuedWaiters(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	l := NewKeyedLimiter(1, 1, time.Second, 0, WithClock(clock)) // evicts idle keys after a second
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l.Allow("a")
+	for i := 0; i < 10; i++ {
+		go l.Wait(ctx, "a")
+	}
+	waitingOn(t, clock, 10)
+	clock.Advance(time.Second) // the first waiter gets its token, nine are still queued
+
+	err := l.Take("a")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.Delay < 9*time.Second {
+		t.Fatalf("got %v, want throttled for as long as the queue takes to drain", err)
+	}
+	if got := l.Len(); got != 1 {
+		t.Fatalf("Len() = %d, want the bucket kept", got)
+	}
+
+	clock.Advance(time.Minute)
+	if got := l.Len(); got != 0 {
+		t.Fatalf("Len() = %d once the bucket filled up again, want 0", got)
+	}
+}
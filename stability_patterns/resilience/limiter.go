@@ -0,0 +1,57 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+/**
+The token bucket is one way of limiting the rate of calls, not the only one.  Every algorithm in this package
+implements Limiter, so Throttle and the other users of a limiter can switch algorithm without their callers noticing:
+
+  - TokenBucket allows bursts of up to max calls and then refill calls every d.
+  - SlidingWindowLog allows exactly limit calls in any window, at the cost of remembering every one of them.
+  - SlidingWindowCounter approximates the sliding window from two counters, so it is cheap however high the limit.
+  - GCRA spaces calls out evenly with room for a burst, remembering a single timestamp.
+  - MultiLimiter only allows calls that all of its tiers allow.
+*/
+
+type Limiter interface {
+	// Take lets a call through if the limit allows it right now, otherwise it returns an error matching
+	// ErrThrottled, which carries a RetryAfterHint when the limiter knows when the next call is allowed.
+	Take() error
+	// Wait lets a call through once the limit allows it, unless ctx is done first.
+	Wait(ctx context.Context) error
+}
+
+// ThrottledError is returned by limiters that know how long until the next call is allowed.
+type ThrottledError struct {
+	Delay time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%v, retry in %v", ErrThrottled, e.Delay)
+}
+
+func (e *ThrottledError) Is(target error) bool { return target == ErrThrottled }
+
+func (e *ThrottledError) RetryAfter() time.Duration { return e.Delay }
+
+// waitFor calls take until it lets the call through, sleeping on clock for as long as take says between attempts.
+func waitFor(ctx context.Context, clock Clock, take func() (time.Duration, bool)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		delay, ok := take()
+		if ok {
+			return nil
+		}
+		select {
+		case <-clock.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
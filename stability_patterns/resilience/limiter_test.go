@@ -0,0 +1,276 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// takeN calls Take n times and fails the test unless every call is let through.
+func takeN(t *testing.T, l Limiter, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := l.Take(); err != nil {
+			t.Fatalf("call %d of %d: %v", i+1, n, err)
+		}
+	}
+}
+
+// throttledFor fails the test unless Take is throttled with the given delay, give or take the odd nanosecond of
+// floating point rounding.
+func throttledFor(t *testing.T, l Limiter, want time.Duration) {
+	t.Helper()
+	err := l.Take()
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("got %v, want a *ThrottledError", err)
+	}
+	if !errors.Is(err, ErrThrottled) {
+		t.Fatalf("%v doesn't match ErrThrottled", err)
+	}
+	if diff := throttled.Delay - want; diff < -time.Microsecond || diff > time.Microsecond {
+		t.Fatalf("delay %v, want %v", throttled.Delay, want)
+	}
+}
+
+// waitingOn fails the test unless n goroutines are soon waiting for clock to move.
+func waitingOn(t *testing.T, clock *ManualClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		clock.m.Lock()
+		waiting := len(clock.waiters)
+		clock.m.Unlock()
+		if waiting == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d waiting on the clock, want %d", waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	l := NewSlidingWindowLog(3, time.Second, WithClock(clock))
+
+	takeN(t, l, 1)
+	clock.Advance(500 * time.Millisecond)
+	takeN(t, l, 2)
+	throttledFor(t, l, 500*time.Millisecond) // until the first call leaves the window
+
+	clock.Advance(499 * time.Millisecond)
+	throttledFor(t, l, time.Millisecond)
+
+	clock.Advance(time.Millisecond) // exactly one window after the first call, which no longer counts
+	takeN(t, l, 1)
+	throttledFor(t, l, 500*time.Millisecond)
+
+	clock.Advance(500 * time.Millisecond)
+	takeN(t, l, 2)
+	throttledFor(t, l, 500*time.Millisecond)
+}
+
+func TestSlidingWindowLogZeroLimit(t *testing.T) {
+	l := NewSlidingWindowLog(0, time.Second, WithClock(NewManualClock(time.Now())))
+	if l.Allow() {
+		t.Fatal("a zero limit let a call through")
+	}
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	c := NewSlidingWindowCounter(10, time.Second, WithClock(clock))
+
+	takeN(t, c, 10)
+	throttledFor(t, c, 1100*time.Millisecond) // full until the next window, which this one still overlaps by 90% after 100ms
+
+	clock.Advance(time.Second)
+	// The previous window still overlaps in full: 10*1 + 0 + 1 > 10.  After 100ms it overlaps by 90%: 9 + 0 + 1 <= 10.
+	throttledFor(t, c, 100*time.Millisecond)
+
+	clock.Advance(150 * time.Millisecond)
+	// 85% overlap: 8.5 + 1 calls so far, one more makes 10.5
+	takeN(t, c, 1)
+	throttledFor(t, c, 50*time.Millisecond)
+
+	clock.Advance(100 * time.Millisecond)
+	// 75% overlap: 7.5 + 1 + 1 <= 10, one more would be 10.5 again
+	takeN(t, c, 1)
+	throttledFor(t, c, 50*time.Millisecond)
+
+	clock.Advance(2 * time.Second) // two windows without a call forget the previous one
+	takeN(t, c, 10)
+}
+
+func TestGCRA(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	g := NewGCRA(10, time.Second, 5, WithClock(clock))
+
+	takeN(t, g, 5) // the burst
+	throttledFor(t, g, 100*time.Millisecond)
+
+	clock.Advance(100 * time.Millisecond)
+	takeN(t, g, 1) // then evenly spaced at the sustained rate
+	throttledFor(t, g, 100*time.Millisecond)
+
+	clock.Advance(50 * time.Millisecond)
+	throttledFor(t, g, 50*time.Millisecond)
+
+	clock.Advance(time.Second) // idle long enough to earn the whole burst back, but no more
+	takeN(t, g, 5)
+	throttledFor(t, g, 100*time.Millisecond)
+}
+
+func TestGCRAWithoutBurst(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	g := NewGCRA(4, time.Second, 0, WithClock(clock))
+
+	takeN(t, g, 1)
+	throttledFor(t, g, 250*time.Millisecond)
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b := NewTokenBucket(3, 1, time.Second, WithClock(clock))
+
+	takeN(t, b, 3)
+	throttledFor(t, b, time.Second)
+
+	clock.Advance(400 * time.Millisecond)
+	throttledFor(t, b, 600*time.Millisecond)
+
+	clock.Advance(600 * time.Millisecond)
+	takeN(t, b, 1)
+	throttledFor(t, b, time.Second)
+
+	clock.Advance(time.Hour) // refills stop at max
+	takeN(t, b, 3)
+	throttledFor(t, b, time.Second)
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b := NewTokenBucket(1, 1, time.Second, WithClock(clock))
+
+	takeN(t, b, 1)
+	r, err := b.Reserve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Delay() != time.Second {
+		t.Fatalf("delay %v, want 1s", r.Delay())
+	}
+	throttledFor(t, b, 2*time.Second) // the next token is spoken for
+
+	r.Cancel()
+	r.Cancel() // only gives the token back once
+	throttledFor(t, b, time.Second)
+}
+
+// waitInBackground calls b.Wait on its own goroutine and reports the outcome on the returned channel.
+func waitInBackground(ctx context.Context, b *TokenBucket) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- b.Wait(ctx) }()
+	return result
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b := NewTokenBucket(1, 1, time.Second, WithClock(clock))
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("full bucket: %v", err)
+	}
+
+	result := waitInBackground(context.Background(), b)
+	waitingOn(t, clock, 1)
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case err := <-result:
+		t.Fatalf("Wait returned %v before the refill", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Millisecond)
+	if err := <-result; err != nil {
+		t.Fatalf("got %v, want the refilled token", err)
+	}
+	throttledFor(t, b, time.Second)
+}
+
+func TestTokenBucketWaitCancelledGivesTokenBack(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b := NewTokenBucket(1, 1, time.Second, WithClock(clock))
+	takeN(t, b, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := waitInBackground(ctx, b)
+	waitingOn(t, clock, 1)
+	throttledFor(t, b, 2*time.Second) // the waiter has the next token reserved
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	throttledFor(t, b, time.Second) // and gave it back
+}
+
+func TestTokenBucketStop(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b := NewTokenBucket(1, 1, time.Second, WithClock(clock))
+	takeN(t, b, 1)
+
+	results := []<-chan error{waitInBackground(context.Background(), b), waitInBackground(context.Background(), b)}
+	waitingOn(t, clock, 2)
+	b.Stop()
+	b.Stop() // stopping twice is fine
+	for i, result := range results {
+		select {
+		case err := <-result:
+			if !errors.Is(err, ErrLimiterStopped) {
+				t.Fatalf("waiter %d: got %v, want %v", i+1, err, ErrLimiterStopped)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("waiter %d is still waiting after Stop", i+1)
+		}
+	}
+
+	clock.Advance(time.Hour)
+	if err := b.Take(); !errors.Is(err, ErrLimiterStopped) {
+		t.Fatalf("Take after Stop: got %v, want %v", err, ErrLimiterStopped)
+	}
+	if err := b.Wait(context.Background()); !errors.Is(err, ErrLimiterStopped) {
+		t.Fatalf("Wait after Stop: got %v, want %v", err, ErrLimiterStopped)
+	}
+}
+
+// Waiting for as long as a limiter says must be enough for the next call to get through.
+func TestLimiterDelaysAreEnough(t *testing.T) {
+	limiters := map[string]func(Clock) Limiter{
+		"token bucket":           func(c Clock) Limiter { return NewTokenBucket(5, 2, 300*time.Millisecond, WithClock(c)) },
+		"sliding window log":     func(c Clock) Limiter { return NewSlidingWindowLog(5, time.Second, WithClock(c)) },
+		"sliding window counter": func(c Clock) Limiter { return NewSlidingWindowCounter(7, time.Second, WithClock(c)) },
+		"gcra":                   func(c Clock) Limiter { return NewGCRA(3, time.Second, 4, WithClock(c)) },
+	}
+	for name, newLimiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			clock := NewManualClock(time.Now())
+			l := newLimiter(clock)
+			for i := 0; i < 200; i++ {
+				clock.Advance(time.Duration(i%7) * 37 * time.Millisecond)
+				err := l.Take()
+				var throttled *ThrottledError
+				if err == nil {
+					continue
+				}
+				if !errors.As(err, &throttled) || throttled.Delay <= 0 {
+					t.Fatalf("step %d: got %v, want a *ThrottledError with a delay", i, err)
+				}
+				clock.Advance(throttled.Delay)
+				if err := l.Take(); err != nil {
+					t.Fatalf("step %d: still throttled after waiting %v: %v", i, throttled.Delay, err)
+				}
+			}
+		})
+	}
+}
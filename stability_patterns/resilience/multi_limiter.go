@@ -0,0 +1,140 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/**
+An API quota is rarely a single number.  "10 a second, 300 a minute and 10,000 a day" needs three limiters, and a call
+may only go through once all three of them agree.  MultiLimiter combines one limiter per tier and takes a token from
+every tier or from none.
+
+A quota counts the calls made in any period, so a tier made by NewTier is a sliding window log: a token bucket of
+limit tokens that also refills limit tokens over the period lets through nearly twice the quota within one period.
+The log remembers every call of the period, so for a very high limit build the Tier with a SlidingWindowCounter
+instead.
+
+When a call is turned away the error says which tier did it and how long until that tier lets calls through again.
+If several tiers are full it names the one with the longest wait, since nothing gets through before then.
+*/
+
+// Tier is one of the limits combined by a MultiLimiter.  Any limiter of this package will do.
+type Tier struct {
+	Name    string
+	Limiter Limiter
+}
+
+// NewTier allows at most limit calls in any period of length per.
+func NewTier(name string, limit uint, per time.Duration, opts ...LimiterOption) Tier {
+	return Tier{Name: name, Limiter: NewSlidingWindowLog(limit, per, opts...)}
+}
+
+// tierLimiter is what a MultiLimiter needs of a tier besides Limiter, to take from every tier or from none and to
+// wait on the right clock.  The limiters of this package all implement it.
+type tierLimiter interface {
+	giveBack()
+	limiterClock() Clock
+}
+
+// TierError is returned when a tier turns a call away.  It is ErrThrottled as far as errors.Is is concerned and it
+// is a RetryAfterHint, so Retry knows how long to wait.
+type TierError struct {
+	Tier  string
+	Delay time.Duration // how long until the tier admits a call again
+
+	clock Clock
+}
+
+func (e *TierError) Error() string {
+	return fmt.Sprintf("%v: %s limit reached, retry in %v", ErrThrottled, e.Tier, e.Delay)
+}
+
+func (e *TierError) Is(target error) bool { return target == ErrThrottled }
+
+func (e *TierError) RetryAfter() time.Duration { return e.Delay }
+
+type MultiLimiter struct {
+	tiers []Tier
+}
+
+func NewMultiLimiter(tiers ...Tier) *MultiLimiter {
+	return &MultiLimiter{tiers: tiers}
+}
+
+// Take takes a token from every tier, or from none of them and returns a *TierError.
+func (m *MultiLimiter) Take() error {
+	for i, t := range m.tiers {
+		err := t.Limiter.Take()
+		if err == nil {
+			continue
+		}
+		m.giveBack(m.tiers[:i])
+		if delay, ok := retryAfter(err); ok && errors.Is(err, ErrThrottled) {
+			return m.rejection(i, delay)
+		}
+		return err // stopped, or a limiter that can't say when it admits calls again
+	}
+	return nil
+}
+
+// Allow reports whether every tier had a token to give.
+func (m *MultiLimiter) Allow() bool {
+	return m.Take() == nil
+}
+
+// Wait takes a token from every tier, waiting until they all have one, until ctx is done.
+func (m *MultiLimiter) Wait(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := m.Take()
+		rejected, ok := err.(*TierError)
+		if !ok {
+			return err
+		}
+
+		select {
+		case <-rejected.clock.After(rejected.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// giveBack returns the tokens taken from tiers when a later tier said no.  A limiter from outside this package keeps
+// its token.
+func (m *MultiLimiter) giveBack(tiers []Tier) {
+	for _, t := range tiers {
+		if l, ok := t.Limiter.(tierLimiter); ok {
+			l.giveBack()
+		}
+	}
+}
+
+// rejection names the tier with the longest wait, asking the tiers after the one that said no, failed, how long they
+// would make a call wait.
+func (m *MultiLimiter) rejection(failed int, delay time.Duration) *TierError {
+	worst := &TierError{Tier: m.tiers[failed].Name, Delay: delay, clock: clockOf(m.tiers[failed].Limiter)}
+	for _, t := range m.tiers[failed+1:] {
+		err := t.Limiter.Take()
+		if err == nil {
+			m.giveBack([]Tier{t})
+			continue
+		}
+		if d, ok := retryAfter(err); ok && d > worst.Delay {
+			worst = &TierError{Tier: t.Name, Delay: d, clock: clockOf(t.Limiter)}
+		}
+	}
+	return worst
+}
+
+func clockOf(l Limiter) Clock {
+	if l, ok := l.(tierLimiter); ok {
+		return l.limiterClock()
+	}
+	return realClock{}
+}
@@ -0,0 +1,128 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// rejectedBy fails the test unless Take is turned away by tier with the given delay.
+func rejectedBy(t *testing.T, m *MultiLimiter, tier string, delay time.Duration) {
+	t.Helper()
+	err := m.Take()
+	var rejected *TierError
+	if !errors.As(err, &rejected) || !errors.Is(err, ErrThrottled) {
+		t.Fatalf("got %v, want a *TierError", err)
+	}
+	if rejected.Tier != tier || rejected.Delay != delay {
+		t.Fatalf("rejected by %s for %v, want %s for %v", rejected.Tier, rejected.Delay, tier, delay)
+	}
+}
+
+func TestNewTierAllowsExactlyLimitPerPeriod(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	m := NewMultiLimiter(NewTier("per day", 100, 24*time.Hour, WithClock(clock)))
+
+	allowed := 0
+	for i := 0; i < 24*60; i++ { // try every minute for a day
+		if m.Allow() {
+			allowed++
+		}
+		clock.Advance(time.Minute)
+	}
+	if allowed != 100 {
+		t.Fatalf("allowed %d calls in a day, want 100", allowed)
+	}
+}
+
+func TestMultiLimiterNamesTheTier(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	m := NewMultiLimiter(
+		NewTier("per second", 2, time.Second, WithClock(clock)),
+		NewTier("per minute", 3, time.Minute, WithClock(clock)),
+	)
+
+	takeN(t, m, 2)
+	rejectedBy(t, m, "per second", time.Second)
+
+	clock.Advance(time.Second)
+	takeN(t, m, 1)
+	rejectedBy(t, m, "per minute", 59*time.Second) // both are full, the minute keeps calls out the longest
+
+	clock.Advance(59 * time.Second)
+	takeN(t, m, 2)
+}
+
+func TestMultiLimiterTakesFromAllOrNone(t *testing.T) {
+	tiers := map[string]func(Clock) Limiter{
+		"token bucket":           func(c Clock) Limiter { return NewTokenBucket(2, 2, time.Minute, WithClock(c)) },
+		"sliding window log":     func(c Clock) Limiter { return NewSlidingWindowLog(2, time.Minute, WithClock(c)) },
+		"sliding window counter": func(c Clock) Limiter { return NewSlidingWindowCounter(2, time.Minute, WithClock(c)) },
+		"gcra":                   func(c Clock) Limiter { return NewGCRA(2, time.Minute, 2, WithClock(c)) },
+	}
+	for name, newLimiter := range tiers {
+		t.Run(name, func(t *testing.T) {
+			clock := NewManualClock(time.Now())
+			m := NewMultiLimiter(
+				Tier{Name: "per minute", Limiter: newLimiter(clock)},
+				NewTier("per second", 1, time.Second, WithClock(clock)),
+			)
+
+			takeN(t, m, 1)
+			for i := 0; i < 3; i++ {
+				rejectedBy(t, m, "per second", time.Second) // mustn't use up the minute's second call
+			}
+			clock.Advance(time.Second)
+			takeN(t, m, 1)
+			clock.Advance(time.Second)
+			if err := m.Take(); !errors.Is(err, ErrThrottled) {
+				t.Fatalf("third call in a minute: got %v, want %v", err, ErrThrottled)
+			}
+		})
+	}
+}
+
+func TestMultiLimiterStopped(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	minute := NewSlidingWindowLog(2, time.Minute, WithClock(clock))
+	stopped := NewTokenBucket(1, 1, time.Second, WithClock(clock))
+	stopped.Stop()
+	m := NewMultiLimiter(Tier{Name: "per minute", Limiter: minute}, Tier{Name: "stopped", Limiter: stopped})
+
+	if err := m.Take(); !errors.Is(err, ErrLimiterStopped) {
+		t.Fatalf("got %v, want %v", err, ErrLimiterStopped)
+	}
+	takeN(t, minute, 2) // the tier in front got its token back
+}
+
+func TestMultiLimiterWait(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	m := NewMultiLimiter(
+		NewTier("per second", 1, time.Second, WithClock(clock)),
+		NewTier("per minute", 2, time.Minute, WithClock(clock)),
+	)
+	takeN(t, m, 1)
+
+	done := make(chan error, 1)
+	go func() { done <- m.Wait(context.Background()) }()
+	waitingOn(t, clock, 1)
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v before the tier let a call through", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("got %v, want the call let through", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- m.Wait(ctx) }()
+	waitingOn(t, clock, 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
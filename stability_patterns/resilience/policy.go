@@ -0,0 +1,210 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/**
+Hand nesting `Retry(Breaker(Throttle(fn, ...), ...), ...)` works, but it is easy to get the order wrong: a breaker
+outside of the retry only ever sees the final outcome, a timeout outside of the retry limits all attempts together
+rather than each one.  A Policy declares the layers from the outside in, checks the order when it is built and tells
+the caller which layer rejected a call.
+
+The only order a policy accepts is
+
+	Fallback -> Retry -> Breaker -> Throttle -> Timeout -> effector
+
+with every layer being optional.  Each layer may appear once.
+*/
+
+type Layer int
+
+const (
+	LayerFallback Layer = iota
+	LayerRetry
+	LayerBreaker
+	LayerThrottle
+	LayerTimeout
+)
+
+func (l Layer) String() string {
+	switch l {
+	case LayerFallback:
+		return "fallback"
+	case LayerRetry:
+		return "retry"
+	case LayerBreaker:
+		return "breaker"
+	case LayerThrottle:
+		return "throttle"
+	case LayerTimeout:
+		return "timeout"
+	}
+	return fmt.Sprintf("Layer(%d)", int(l))
+}
+
+var (
+	ErrRetriesExhausted = errors.New("retries exhausted")
+	ErrInvalidPolicy    = errors.New("invalid policy")
+)
+
+// RejectedError tells which layer of a policy turned a call down.  It matches the layer's error with errors.Is, so
+// errors.Is(err, ErrOpen) holds for a call rejected by the breaker and errors.Is(err, ErrRetriesExhausted) for one
+// that failed on every attempt.
+type RejectedError struct {
+	Layer Layer
+	Err   error // the error of the last attempt when retries ran out, otherwise the layer's own error
+}
+
+func (e *RejectedError) Error() string {
+	if e.Layer == LayerRetry {
+		return fmt.Sprintf("%v: %v: %v", e.Layer, ErrRetriesExhausted, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Layer, e.Err)
+}
+
+func (e *RejectedError) Unwrap() error { return e.Err }
+
+func (e *RejectedError) Is(target error) bool {
+	return e.Layer == LayerRetry && target == ErrRetriesExhausted
+}
+
+type policyLayer[T any] struct {
+	kind Layer
+	wrap func(Effector[T]) Effector[T]
+}
+
+type Policy[T any] struct {
+	layers []policyLayer[T]
+	errs   []error
+}
+
+// NewPolicy starts an empty policy.  Add layers outermost first and finish with Build.
+func NewPolicy[T any]() *Policy[T] {
+	return &Policy[T]{}
+}
+
+// Retry retries failed calls, see Retry.  It must be declared before Breaker, Throttle and Timeout.  Calls turned away
+// by an open breaker or a stopped limiter below it are never retried, the next attempt would be turned away too.
+func (p *Policy[T]) Retry(retries int, delay time.Duration, opts ...RetryOption) *Policy[T] {
+	if retries < 0 {
+		p.errs = append(p.errs, fmt.Errorf("retry: negative retries %d", retries))
+	}
+	opts = append(opts[:len(opts):len(opts)], neverRetry(ErrOpen, ErrLimiterStopped))
+	return p.add(LayerRetry, func(next Effector[T]) Effector[T] {
+		return func(ctx context.Context) (T, error) {
+			attempts := 0
+			counted := func(ctx context.Context) (T, error) {
+				attempts++
+				return next(ctx)
+			}
+			response, err := Retry(counted, retries, delay, opts...)(ctx)
+			if err != nil && attempts > retries {
+				return response, &RejectedError{Layer: LayerRetry, Err: err}
+			}
+			return response, err
+		}
+	})
+}
+
+// Breaker puts a circuit breaker around the layers below it, see NewCircuitBreaker.  Calls turned away by the throttle
+// below it never count against the circuit.
+func (p *Policy[T]) Breaker(failureThreshold uint, opts ...BreakerOption) *Policy[T] {
+	opts = append(opts[:len(opts):len(opts)], ignoreLocal(ErrThrottled, ErrLimiterStopped))
+	return p.add(LayerBreaker, func(next Effector[T]) Effector[T] {
+		return rejectOn(LayerBreaker, ErrOpen, Breaker(next, failureThreshold, opts...))
+	})
+}
+
+// Throttle limits the rate of calls reaching the layers below it, see Throttle.
+func (p *Policy[T]) Throttle(max uint, refill uint, d time.Duration, opts ...ThrottleOption) *Policy[T] {
+	return p.add(LayerThrottle, func(next Effector[T]) Effector[T] {
+		return rejectOn(LayerThrottle, ErrThrottled, Throttle(next, max, refill, d, opts...))
+	})
+}
+
+// ThrottleWith limits the rate of calls reaching the layers below it with limiter, see ThrottleWith.  Unlike Throttle,
+// every policy built shares limiter.
+func (p *Policy[T]) ThrottleWith(limiter Limiter, opts ...ThrottleOption) *Policy[T] {
+	if limiter == nil {
+		p.errs = append(p.errs, errors.New("throttle: nil limiter"))
+	}
+	return p.add(LayerThrottle, func(next Effector[T]) Effector[T] {
+		return rejectOn(LayerThrottle, ErrThrottled, ThrottleWith(next, limiter, opts...))
+	})
+}
+
+// Timeout gives every attempt at most d.
+func (p *Policy[T]) Timeout(d time.Duration) *Policy[T] {
+	if d <= 0 {
+		p.errs = append(p.errs, fmt.Errorf("timeout: non-positive duration %v", d))
+	}
+	return p.add(LayerTimeout, func(next Effector[T]) Effector[T] {
+		return rejectOn(LayerTimeout, ErrTimeout, Timeout(next, d))
+	})
+}
+
+// Fallback answers calls that failed despite all the other layers with what fn returns for their error.  It must be
+// declared first.  Unlike the Fallback function it keeps the type of the effector, so it has no Result telling a
+// degraded answer from a real one, and it calls fn for every error, whatever its class.
+func (p *Policy[T]) Fallback(fn func(ctx context.Context, err error) (T, error)) *Policy[T] {
+	if fn == nil {
+		p.errs = append(p.errs, errors.New("fallback: nil function"))
+	}
+	return p.add(LayerFallback, func(next Effector[T]) Effector[T] {
+		return func(ctx context.Context) (T, error) {
+			response, err := next(ctx)
+			if err == nil {
+				return response, nil
+			}
+			return fn(ctx, err)
+		}
+	})
+}
+
+func (p *Policy[T]) add(kind Layer, wrap func(Effector[T]) Effector[T]) *Policy[T] {
+	p.layers = append(p.layers, policyLayer[T]{kind: kind, wrap: wrap})
+	return p
+}
+
+// Build checks the declared layers and wraps effector in them.  Every call to Build gets its own breaker and throttle.
+func (p *Policy[T]) Build(effector Effector[T]) (Effector[T], error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	for i := len(p.layers) - 1; i >= 0; i-- { // wrap from the inside out
+		effector = p.layers[i].wrap(effector)
+	}
+	return effector, nil
+}
+
+func (p *Policy[T]) validate() error {
+	if len(p.errs) > 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidPolicy, p.errs[0])
+	}
+	for i := 1; i < len(p.layers); i++ {
+		outer, inner := p.layers[i-1].kind, p.layers[i].kind
+		if outer == inner {
+			return fmt.Errorf("%w: %v declared twice", ErrInvalidPolicy, inner)
+		}
+		if outer > inner {
+			return fmt.Errorf("%w: %v must be declared before %v", ErrInvalidPolicy, inner, outer)
+		}
+	}
+	return nil
+}
+
+// rejectOn reports the layer when e fails with the layer's own error rather than one already reported further down.
+func rejectOn[T any](layer Layer, sentinel error, e Effector[T]) Effector[T] {
+	return func(ctx context.Context) (T, error) {
+		response, err := e(ctx)
+		var rejected *RejectedError
+		if errors.Is(err, sentinel) && !errors.As(err, &rejected) {
+			return response, &RejectedError{Layer: layer, Err: err}
+		}
+		return response, err
+	}
+}
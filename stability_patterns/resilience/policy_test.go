@@ -0,0 +1,74 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicyThrottleDoesNotTripBreaker(t *testing.T) {
+	call, err := NewPolicy[string]().
+		Breaker(2).
+		Throttle(1, 1, time.Hour).
+		Build(func(ctx context.Context) (string, error) { return "ok", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := call(context.Background()); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := call(context.Background())
+		var rejected *RejectedError
+		if !errors.As(err, &rejected) || rejected.Layer != LayerThrottle {
+			t.Fatalf("call %d: got %v, want a rejection by the throttle", i+2, err)
+		}
+	}
+}
+
+func TestPolicyOrder(t *testing.T) {
+	_, err := NewPolicy[string]().
+		Throttle(1, 1, time.Second).
+		Retry(1, 0).
+		Build(func(ctx context.Context) (string, error) { return "ok", nil })
+	if !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidPolicy)
+	}
+}
+
+func TestPolicyDoesNotRetryOpenBreaker(t *testing.T) {
+	calls := 0
+	call, err := NewPolicy[string]().
+		Retry(3, 100*time.Millisecond, WithRetryClassifier(Is(errUpstream, Failure))).
+		Breaker(1, WithOpenBackoff(time.Hour)).
+		Build(func(ctx context.Context) (string, error) {
+			calls++
+			return "", errUpstream
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := call(context.Background())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		var rejected *RejectedError
+		if !errors.As(err, &rejected) || rejected.Layer != LayerBreaker || !errors.Is(err, ErrOpen) {
+			t.Fatalf("got %v, want a rejection by the breaker", err)
+		}
+		if errors.Is(err, ErrRetriesExhausted) {
+			t.Fatalf("got %v, want the retries not to be blamed", err)
+		}
+	case <-time.After(250 * time.Millisecond): // one wait after the upstream failed, none after the circuit opened
+		t.Fatal("still retrying against an open circuit")
+	}
+	if calls != 1 {
+		t.Fatalf("%d calls reached the upstream, want 1", calls)
+	}
+}
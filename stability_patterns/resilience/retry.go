@@ -0,0 +1,191 @@
+package resilience
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+type retryConfig struct {
+	classifier Classifier
+	backoff    Backoff
+	maxDelay   time.Duration
+	maxElapsed time.Duration
+	hintCap    time.Duration
+	budget     *RetryBudget
+	observers  retryObservers
+}
+
+type RetryOption func(*retryConfig)
+
+// WithRetryClassifier decides which errors are worth retrying.  The default classifier is consulted after it, so a
+// cancelled context is never retried.
+func WithRetryClassifier(classifiers ...Classifier) RetryOption {
+	return func(c *retryConfig) {
+		c.classifier = Chain(classifiers...)
+	}
+}
+
+// WithBackoff replaces the constant delay between attempts with another strategy.
+func WithBackoff(b Backoff) RetryOption {
+	return func(c *retryConfig) {
+		if b != nil {
+			c.backoff = b
+		}
+	}
+}
+
+// WithMaxDelay caps the wait between two attempts.
+func WithMaxDelay(d time.Duration) RetryOption {
+	return func(c *retryConfig) {
+		c.maxDelay = d
+	}
+}
+
+// WithMaxElapsed gives up once the next attempt would start more than d after the first one.  The attempts see it as
+// a deadline on their context, so a Timeout inside the Retry can share it out.
+func WithMaxElapsed(d time.Duration) RetryOption {
+	return func(c *retryConfig) {
+		c.maxElapsed = d
+	}
+}
+
+// WithRetryAfterCeiling sets the longest delay an upstream may ask for through a RetryAfterHint.  Retry gives up
+// straight away when asked to wait longer.  Without a ceiling every hint is honoured.
+func WithRetryAfterCeiling(d time.Duration) RetryOption {
+	return func(c *retryConfig) {
+		c.hintCap = d
+	}
+}
+
+// WithRetryBudget shares b between this and other Retry wrappers.  Once it runs dry retries stop and the error returned
+// matches ErrRetryBudgetExhausted as well as the error of the last attempt.
+func WithRetryBudget(b *RetryBudget) RetryOption {
+	return func(c *retryConfig) {
+		c.budget = b
+	}
+}
+
+// neverRetry makes errs Permanent, whatever the classifiers given with WithRetryClassifier say.  It must come after
+// them.
+func neverRetry(errs ...error) RetryOption {
+	return func(c *retryConfig) {
+		classifiers := make([]Classifier, 0, len(errs)+1)
+		for _, err := range errs {
+			classifiers = append(classifiers, Is(err, Permanent))
+		}
+		c.classifier = Chain(append(classifiers, c.classifier)...)
+	}
+}
+
+// WithObserver is told about every attempt.  It can be given more than once.
+func WithObserver(o RetryObserver) RetryOption {
+	return func(c *retryConfig) {
+		c.observers = append(c.observers, o)
+	}
+}
+
+// Retry calls effector up to retries+1 times until it succeeds.  It waits delay between attempts unless WithBackoff
+// picks another strategy.  When the error carries a RetryAfterHint, the next attempt waits at least that long.  It
+// gives up straight away when the wait would outlast the deadline of ctx or the WithMaxElapsed budget.
+func Retry[T any](effector Effector[T], retries int, delay time.Duration, opts ...RetryOption) Effector[T] {
+	config := retryConfig{backoff: Constant(delay)}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(ctx context.Context) (T, error) {
+		start := time.Now()
+		if config.budget != nil {
+			config.budget.request()
+		}
+		giveUp := func(response T, err error, attempts int) (T, error) {
+			config.observers.giveUp(ctx, attempts, err)
+			return response, err
+		}
+
+		attemptCtx := ctx
+		if config.maxElapsed > 0 {
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithDeadline(ctx, start.Add(config.maxElapsed))
+			defer cancel()
+		}
+
+		var wait time.Duration
+		for r := 0; ; r++ {
+			attempt := r + 1
+			config.observers.beforeAttempt(ctx, attempt)
+			began := time.Now()
+			response, err := effector(context.WithValue(attemptCtx, attemptKey{}, attemptInfo{attempt, retries + 1}))
+			config.observers.afterAttempt(ctx, attempt, time.Since(began), err)
+			if err == nil {
+				if attempt > 1 {
+					config.observers.successAfterRetries(ctx, attempt)
+				}
+				return response, nil
+			}
+			if r >= retries || retriesDisabled(ctx) || !Classify(err, config.classifier).ShouldRetry() {
+				return giveUp(response, err, attempt)
+			}
+			wait = config.backoff(attempt, wait, config.maxDelay)
+			if hint, ok := retryAfter(err); ok {
+				if hint == math.MaxInt64 || config.hintCap > 0 && hint > config.hintCap {
+					return giveUp(response, err, attempt) // the upstream wants more time than we are willing to wait
+				}
+				if hint > wait {
+					wait = hint
+				}
+			}
+			if wait > remaining(ctx, start, config.maxElapsed) {
+				return giveUp(response, err, attempt) // the next attempt would blow the time budget
+			}
+			if config.budget != nil && !config.budget.withdraw() {
+				return giveUp(response, &retryBudgetError{err}, attempt)
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				var zero T
+				return giveUp(zero, ctx.Err(), attempt)
+			}
+		}
+	}
+}
+
+// remaining is how long until the time budget, or the deadline of ctx if that comes first, runs out.
+func remaining(ctx context.Context, start time.Time, maxElapsed time.Duration) time.Duration {
+	left := time.Duration(math.MaxInt64)
+	if maxElapsed > 0 {
+		left = maxElapsed - time.Since(start)
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < left {
+		left = time.Until(deadline)
+	}
+	return left
+}
+
+type noRetryKey struct{}
+
+// withoutRetries tells every Retry handling ctx to give up after the first attempt, for calls that mustn't be made
+// twice.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+func retriesDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noRetryKey{}).(bool)
+	return disabled
+}
+
+type attemptKey struct{}
+
+type attemptInfo struct {
+	attempt, max int
+}
+
+// AttemptFrom tells an effector running inside a Retry which attempt this is, counting from 1, and how many attempts
+// it gets at most.
+func AttemptFrom(ctx context.Context) (attempt, max int, ok bool) {
+	info, ok := ctx.Value(attemptKey{}).(attemptInfo)
+	return info.attempt, info.max, ok
+}
@@ -0,0 +1,78 @@
+package resilience
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+An upstream answering 429 Too Many Requests or 503 Service Unavailable often says how long it wants to be left alone
+with a Retry-After header.  Retrying earlier than that is wasted work for both sides, so an effector can hand the hint
+to Retry through the error it returns.
+*/
+
+// RetryAfterHint is implemented by errors that know how long to wait before the next attempt.
+type RetryAfterHint interface {
+	RetryAfter() time.Duration
+}
+
+// RetryAfterError wraps the error of a call with the delay the upstream asked for.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %v)", e.Err, e.Delay)
+}
+
+func (e *RetryAfterError) Unwrap() error { return e.Err }
+
+func (e *RetryAfterError) RetryAfter() time.Duration { return e.Delay }
+
+// retryAfter finds a delay hint anywhere in err's chain.
+func retryAfter(err error) (time.Duration, bool) {
+	var hint RetryAfterHint
+	if !errors.As(err, &hint) {
+		return 0, false
+	}
+	if d := hint.RetryAfter(); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// ParseRetryAfter reads a Retry-After header value, which is either a number of seconds or an HTTP date.  Dates are
+// turned into a delay from now, dates in the past into no delay at all.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// ResponseRetryAfter reads the Retry-After header of resp.
+func ResponseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	return ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+}
@@ -0,0 +1,97 @@
+package resilience
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/**
+Every Retry wrapper on its own is reasonable, but during an outage each of them multiplies the load on the upstream by
+retries+1 right when it can least afford it.  A RetryBudget is shared by many Retry wrappers and only allows retries to
+be a fraction of the requests made recently, plus a small floor so that retries still happen when traffic is low.
+*/
+
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+const retryBudgetBuckets = 10
+
+type RetryBudget struct {
+	ratio     float64
+	minPerSec float64
+	ttl       time.Duration
+	width     time.Duration
+	now       func() time.Time
+
+	m       sync.Mutex
+	buckets [retryBudgetBuckets]budgetBucket
+}
+
+type budgetBucket struct {
+	epoch             int64
+	requests, retries uint
+}
+
+// NewRetryBudget allows retries to be ratio of the requests made in the last ttl, plus minRetriesPerSecond.  A ratio
+// of 0.1 with a floor of 10 lets 100 requests a second retry 20 times a second between them.
+func NewRetryBudget(ratio float64, minRetriesPerSecond float64, ttl time.Duration) *RetryBudget {
+	width := ttl / retryBudgetBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &RetryBudget{ratio: ratio, minPerSec: minRetriesPerSecond, ttl: ttl, width: width, now: time.Now}
+}
+
+// request records a first attempt, which earns the budget ratio retries.
+func (b *RetryBudget) request() {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.bucket().requests++
+}
+
+// withdraw spends a retry if the budget allows it.
+func (b *RetryBudget) withdraw() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	current := b.bucket()
+	var requests, retries uint
+	for _, bucket := range b.buckets {
+		if current.epoch-bucket.epoch < retryBudgetBuckets {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+
+	allowed := b.minPerSec*b.ttl.Seconds() + b.ratio*float64(requests)
+	if float64(retries)+1 > allowed {
+		return false
+	}
+	current.retries++
+	return true
+}
+
+// bucket returns the bucket for the current time, emptying it if it was last used a whole ttl ago.
+func (b *RetryBudget) bucket() *budgetBucket {
+	epoch := b.now().UnixNano() / int64(b.width)
+	bucket := &b.buckets[epoch%retryBudgetBuckets]
+	if bucket.epoch != epoch {
+		bucket.epoch = epoch
+		bucket.requests, bucket.retries = 0, 0
+	}
+	return bucket
+}
+
+// retryBudgetError is both ErrRetryBudgetExhausted and the error of the last attempt.
+type retryBudgetError struct {
+	err error
+}
+
+func (e *retryBudgetError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrRetryBudgetExhausted, e.err)
+}
+
+func (e *retryBudgetError) Unwrap() error { return e.err }
+
+func (e *retryBudgetError) Is(target error) bool { return target == ErrRetryBudgetExhausted }
@@ -0,0 +1,108 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestBudget(ratio, minPerSec float64, ttl time.Duration) (*RetryBudget, *ManualClock) {
+	clock := NewManualClock(time.Unix(0, 0))
+	b := NewRetryBudget(ratio, minPerSec, ttl)
+	b.now = clock.Now
+	return b, clock
+}
+
+// withdrawals is how many retries b lets through before running dry.
+func withdrawals(b *RetryBudget) int {
+	n := 0
+	for b.withdraw() {
+		n++
+	}
+	return n
+}
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		ratio     float64
+		minPerSec float64
+		requests  int
+		want      int
+	}{
+		{"no traffic, no floor", 0.1, 0, 0, 0},
+		{"floor only", 0.1, 2, 0, 20},
+		{"ratio only", 0.2, 0, 100, 20},
+		{"ratio and floor", 0.1, 1, 100, 20},
+		{"fractional allowance rounds down", 0.1, 0, 15, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := newTestBudget(tt.ratio, tt.minPerSec, 10*time.Second)
+			for i := 0; i < tt.requests; i++ {
+				b.request()
+			}
+			if got := withdrawals(b); got != tt.want {
+				t.Fatalf("got %d retries, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBudgetRefillsAsRetriesAge(t *testing.T) {
+	b, clock := newTestBudget(0.1, 0, 10*time.Second)
+	for i := 0; i < 100; i++ {
+		b.request()
+	}
+	if got := withdrawals(b); got != 10 {
+		t.Fatalf("got %d retries, want 10", got)
+	}
+
+	clock.Advance(5 * time.Second)
+	for i := 0; i < 100; i++ {
+		b.request()
+	}
+	if got := withdrawals(b); got != 10 {
+		t.Fatalf("half a ttl later: got %d retries, want 10 earned by the new requests", got)
+	}
+
+	clock.Advance(5 * time.Second)
+	if got := withdrawals(b); got != 0 {
+		t.Fatalf("a ttl after the first requests: got %d retries, want 0 as their retries aged out with them", got)
+	}
+
+	clock.Advance(10 * time.Second)
+	for i := 0; i < 10; i++ {
+		b.request()
+	}
+	if got := withdrawals(b); got != 1 {
+		t.Fatalf("after an idle ttl: got %d retries, want 1", got)
+	}
+}
+
+func TestRetryStopsWhenBudgetRunsDry(t *testing.T) {
+	b, _ := newTestBudget(0, 0.1, 10*time.Second) // a single retry between all the wrappers
+	calls := 0
+	failing := func(ctx context.Context) (string, error) {
+		calls++
+		return "", errUpstream
+	}
+	first := Retry(failing, 3, time.Millisecond, WithRetryBudget(b))
+	second := Retry(failing, 3, time.Millisecond, WithRetryBudget(b))
+
+	if _, err := first(context.Background()); !errors.Is(err, ErrRetryBudgetExhausted) || !errors.Is(err, errUpstream) {
+		t.Fatalf("got %v, want both %v and %v", err, ErrRetryBudgetExhausted, errUpstream)
+	}
+	if calls != 2 {
+		t.Fatalf("first wrapper made %d calls, want 2", calls)
+	}
+
+	calls = 0
+	if _, err := second(context.Background()); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("got %v, want %v", err, ErrRetryBudgetExhausted)
+	}
+	if calls != 1 {
+		t.Fatalf("second wrapper made %d calls, want 1 as the shared budget is spent", calls)
+	}
+}
@@ -0,0 +1,98 @@
+package resilience
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+/**
+Retry used to log every failed attempt with log.Printf, which could neither be silenced nor counted.  It now reports
+what it is doing to observers instead, and LogObserver and StructuredLogObserver turn those reports back into log
+lines for whoever wants them.
+*/
+
+// RetryObserver is told about the attempts a Retry makes.  Any of the hooks may be left nil.  Attempts count from 1.
+type RetryObserver struct {
+	BeforeAttempt       func(ctx context.Context, attempt int)
+	AfterAttempt        func(ctx context.Context, attempt int, took time.Duration, err error)
+	GiveUp              func(ctx context.Context, attempts int, err error)
+	SuccessAfterRetries func(ctx context.Context, attempts int)
+}
+
+type retryObservers []RetryObserver
+
+func (observers retryObservers) beforeAttempt(ctx context.Context, attempt int) {
+	for _, o := range observers {
+		if o.BeforeAttempt != nil {
+			o.BeforeAttempt(ctx, attempt)
+		}
+	}
+}
+
+func (observers retryObservers) afterAttempt(ctx context.Context, attempt int, took time.Duration, err error) {
+	for _, o := range observers {
+		if o.AfterAttempt != nil {
+			o.AfterAttempt(ctx, attempt, took, err)
+		}
+	}
+}
+
+func (observers retryObservers) giveUp(ctx context.Context, attempts int, err error) {
+	for _, o := range observers {
+		if o.GiveUp != nil {
+			o.GiveUp(ctx, attempts, err)
+		}
+	}
+}
+
+func (observers retryObservers) successAfterRetries(ctx context.Context, attempts int) {
+	for _, o := range observers {
+		if o.SuccessAfterRetries != nil {
+			o.SuccessAfterRetries(ctx, attempts)
+		}
+	}
+}
+
+// LogObserver writes failed attempts and their outcome to l, or to the standard logger when l is nil.
+func LogObserver(l *log.Logger) RetryObserver {
+	if l == nil {
+		l = log.Default()
+	}
+	return RetryObserver{
+		AfterAttempt: func(_ context.Context, attempt int, took time.Duration, err error) {
+			if err != nil {
+				l.Printf("Attempt %d failed after %v: %v", attempt, took, err)
+			}
+		},
+		GiveUp: func(_ context.Context, attempts int, err error) {
+			l.Printf("Giving up after %d attempts: %v", attempts, err)
+		},
+		SuccessAfterRetries: func(_ context.Context, attempts int) {
+			l.Printf("Succeeded after %d attempts", attempts)
+		},
+	}
+}
+
+// StructuredLogger is the part of a log/slog style logger the observer needs.  *slog.Logger satisfies it.
+type StructuredLogger interface {
+	InfoContext(ctx context.Context, msg string, args ...any)
+	WarnContext(ctx context.Context, msg string, args ...any)
+}
+
+// StructuredLogObserver writes failed attempts and their outcome to l as key value pairs.
+func StructuredLogObserver(l StructuredLogger) RetryObserver {
+	return RetryObserver{
+		AfterAttempt: func(ctx context.Context, attempt int, took time.Duration, err error) {
+			if err != nil {
+				l.InfoContext(ctx, "attempt failed", "attempt", attempt, "took", took, "error", err)
+			}
+		},
+		GiveUp: func(ctx context.Context, attempts int, err error) {
+			l.WarnContext(ctx, "giving up", "attempts", attempts, "error", err)
+		},
+		SuccessAfterRetries: func(ctx context.Context, attempts int) {
+			l.InfoContext(ctx, "succeeded after retries", "attempts", attempts)
+		},
+	}
+}
@@ -0,0 +1,175 @@
+package resilience
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder writes down every hook call as a line of text.
+type recorder struct {
+	events []string
+	took   []time.Duration
+}
+
+func (r *recorder) observer(name string) RetryObserver {
+	return RetryObserver{
+		BeforeAttempt: func(_ context.Context, attempt int) {
+			r.events = append(r.events, fmt.Sprintf("%s before %d", name, attempt))
+		},
+		AfterAttempt: func(_ context.Context, attempt int, took time.Duration, err error) {
+			r.events = append(r.events, fmt.Sprintf("%s after %d: %v", name, attempt, err))
+			r.took = append(r.took, took)
+		},
+		GiveUp: func(_ context.Context, attempts int, err error) {
+			r.events = append(r.events, fmt.Sprintf("%s give up after %d: %v", name, attempts, err))
+		},
+		SuccessAfterRetries: func(_ context.Context, attempts int) {
+			r.events = append(r.events, fmt.Sprintf("%s success after %d", name, attempts))
+		},
+	}
+}
+
+// failing fails the first failures calls, taking d over each of them.
+func failing(failures int, d time.Duration) Effector[string] {
+	calls := 0
+	return func(ctx context.Context) (string, error) {
+		calls++
+		if calls <= failures {
+			time.Sleep(d)
+			return "", errUpstream
+		}
+		return "ok", nil
+	}
+}
+
+func TestRetryObserverHooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     []string
+	}{
+		{"first try", 0, []string{
+			"o before 1", "o after 1: <nil>",
+		}},
+		{"after retries", 2, []string{
+			"o before 1", "o after 1: " + errUpstream.Error(),
+			"o before 2", "o after 2: " + errUpstream.Error(),
+			"o before 3", "o after 3: <nil>",
+			"o success after 3",
+		}},
+		{"giving up", 5, []string{
+			"o before 1", "o after 1: " + errUpstream.Error(),
+			"o before 2", "o after 2: " + errUpstream.Error(),
+			"o before 3", "o after 3: " + errUpstream.Error(),
+			"o give up after 3: " + errUpstream.Error(),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			Retry(failing(tt.failures, 0), 2, 0, WithObserver(r.observer("o")))(context.Background())
+			if !slices.Equal(r.events, tt.want) {
+				t.Fatalf("got\n\t%s\nwant\n\t%s", strings.Join(r.events, "\n\t"), strings.Join(tt.want, "\n\t"))
+			}
+		})
+	}
+}
+
+func TestRetryObserverDurations(t *testing.T) {
+	var r recorder
+	Retry(failing(1, 20*time.Millisecond), 1, 0, WithObserver(r.observer("o")))(context.Background())
+	if len(r.took) != 2 {
+		t.Fatalf("got %d durations, want 2", len(r.took))
+	}
+	if r.took[0] < 20*time.Millisecond {
+		t.Errorf("failed attempt took %v, want at least 20ms", r.took[0])
+	}
+	if r.took[1] >= 20*time.Millisecond {
+		t.Errorf("quick attempt took %v, want less than 20ms", r.took[1])
+	}
+}
+
+func TestRetryObserversAreCalledInOrder(t *testing.T) {
+	var r recorder
+	Retry(failing(1, 0), 1, 0, WithObserver(r.observer("a")), WithObserver(r.observer("b")))(context.Background())
+	want := []string{
+		"a before 1", "b before 1", "a after 1: " + errUpstream.Error(), "b after 1: " + errUpstream.Error(),
+		"a before 2", "b before 2", "a after 2: <nil>", "b after 2: <nil>",
+		"a success after 2", "b success after 2",
+	}
+	if !slices.Equal(r.events, want) {
+		t.Fatalf("got\n\t%s\nwant\n\t%s", strings.Join(r.events, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
+
+func TestRetryObserverSkipsNilHooks(t *testing.T) {
+	Retry(failing(5, 0), 1, 0, WithObserver(RetryObserver{}))(context.Background())
+}
+
+func TestLogObserver(t *testing.T) {
+	var out bytes.Buffer
+	o := LogObserver(log.New(&out, "", 0))
+	Retry(failing(5, 0), 1, 0, WithObserver(o))(context.Background())
+	Retry(failing(1, 0), 1, 0, WithObserver(o))(context.Background())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	wantPrefixes := []string{
+		"Attempt 1 failed after ",
+		"Attempt 2 failed after ",
+		"Giving up after 2 attempts: " + errUpstream.Error(),
+		"Attempt 1 failed after ",
+		"Succeeded after 2 attempts",
+	}
+	if len(lines) != len(wantPrefixes) {
+		t.Fatalf("logged\n\t%s\nwant %d lines", strings.Join(lines, "\n\t"), len(wantPrefixes))
+	}
+	for i, prefix := range wantPrefixes {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("line %d: %q, want it to start with %q", i+1, lines[i], prefix)
+		}
+	}
+}
+
+func TestStructuredLogObserver(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "took" {
+				return slog.Attr{} // they change from run to run
+			}
+			return a
+		},
+	}))
+	o := StructuredLogObserver(logger)
+	Retry(failing(5, 0), 1, 0, WithObserver(o))(context.Background())
+	Retry(failing(1, 0), 1, 0, WithObserver(o))(context.Background())
+
+	upstream := fmt.Sprintf("%q", errUpstream.Error())
+	want := []string{
+		`level=INFO msg="attempt failed" attempt=1 error=` + upstream,
+		`level=INFO msg="attempt failed" attempt=2 error=` + upstream,
+		`level=WARN msg="giving up" attempts=2 error=` + upstream,
+		`level=INFO msg="attempt failed" attempt=1 error=` + upstream,
+		`level=INFO msg="succeeded after retries" attempts=2`,
+	}
+	if got := strings.Split(strings.TrimSpace(out.String()), "\n"); !slices.Equal(got, want) {
+		t.Fatalf("logged\n\t%s\nwant\n\t%s", strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
+
+func TestObserverSeesBudgetError(t *testing.T) {
+	b := NewRetryBudget(0, 0, time.Second)
+	var r recorder
+	Retry(failing(5, 0), 3, 0, WithRetryBudget(b), WithObserver(r.observer("o")))(context.Background())
+	last := r.events[len(r.events)-1]
+	if !strings.HasPrefix(last, "o give up after 1: "+ErrRetryBudgetExhausted.Error()) {
+		t.Fatalf("last event %q, want giving up on the exhausted budget", last)
+	}
+}
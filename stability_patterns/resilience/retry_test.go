@@ -0,0 +1,63 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryGivesUpOnUnboundedHint(t *testing.T) {
+	throttled := Throttle(func(ctx context.Context) (string, error) { return "ok", nil }, 1, 0, time.Second)
+	r := Retry(throttled, 2, time.Millisecond, WithMaxElapsed(200*time.Millisecond))
+
+	if _, err := r(context.Background()); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := r(context.Background())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrThrottled) {
+			t.Fatalf("got %v, want %v", err, ErrThrottled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("retry is still waiting for a token that never comes")
+	}
+}
+
+func TestRetryGivesUpWhenHintOutlastsBudget(t *testing.T) {
+	calls := 0
+	r := Retry(func(ctx context.Context) (string, error) {
+		calls++
+		return "", &RetryAfterError{Err: errUpstream, Delay: time.Hour}
+	}, 3, time.Millisecond, WithMaxElapsed(time.Second))
+
+	start := time.Now()
+	if _, err := r(context.Background()); !errors.Is(err, errUpstream) {
+		t.Fatalf("got %v, want %v", err, errUpstream)
+	}
+	if calls != 1 || time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("made %d calls in %v, want 1 call and no wait", calls, time.Since(start))
+	}
+}
+
+func TestRetryGivesUpBeforeDeadline(t *testing.T) {
+	calls := 0
+	r := Retry(func(ctx context.Context) (string, error) {
+		calls++
+		return "", errUpstream
+	}, 3, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := r(ctx); !errors.Is(err, errUpstream) {
+		t.Fatalf("got %v, want the error of the last attempt", err)
+	}
+	if calls != 1 {
+		t.Fatalf("made %d calls, want 1", calls)
+	}
+}
@@ -0,0 +1,259 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/**
+An overloaded service that keeps queueing requests ends up serving nothing but requests whose callers have already
+given up.  A Shedder lets a fixed number of calls in at once and queues the rest, using Controlled Delay (CoDel) to
+tell a queue that absorbs a burst from a queue that is standing:
+
+  - As long as the queue drains completely every interval, queued calls are served first in, first out and may wait
+    up to interval for their turn.
+  - Once the queue has not been empty for a whole interval the shedder is overloaded.  Calls that have already been
+    queued for longer than target are shed rather than served, and with LIFO enabled the newest calls, whose callers
+    are most likely still waiting for them, are served first.
+
+Calls carry a priority in their context.  Critical calls are served before everything else and are never shed, they
+wait for as long as their context allows.  Sheddable calls are turned away as soon as the shedder is overloaded.
+*/
+
+var ErrShed = errors.New("load shed")
+
+type Priority int
+
+const (
+	Sheddable Priority = iota // shed first, eg. prefetching or batch work
+	Normal                    // the default
+	Critical                  // shed last, eg. health checks or checkout
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context carrying priority p for the shedder.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority carried by ctx, Normal if there is none.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return Normal
+}
+
+type shedderConfig struct {
+	target   time.Duration
+	interval time.Duration
+	lifo     bool
+	clock    Clock
+}
+
+type ShedderOption func(*shedderConfig)
+
+// WithCoDel sets the CoDel target and interval, 5ms and 100ms by default.
+func WithCoDel(target, interval time.Duration) ShedderOption {
+	return func(c *shedderConfig) {
+		if target > 0 && interval > 0 {
+			c.target, c.interval = target, interval
+		}
+	}
+}
+
+// WithShedderClock makes the shedder read the time from c instead of the system clock.
+func WithShedderClock(c Clock) ShedderOption {
+	return func(config *shedderConfig) {
+		if c != nil {
+			config.clock = c
+		}
+	}
+}
+
+// WithLIFO serves the newest queued calls first while the shedder is overloaded.
+func WithLIFO() ShedderOption {
+	return func(c *shedderConfig) {
+		c.lifo = true
+	}
+}
+
+type Shedder struct {
+	maxConcurrent int
+	config        shedderConfig
+
+	m         sync.Mutex
+	inflight  int
+	queue     []*shedWaiter
+	lastEmpty time.Time // the last time the queue was empty
+}
+
+type shedWaiter struct {
+	priority Priority
+	enqueued time.Time
+	ready    chan struct{} // closed once the call got a place
+	err      error         // set instead when the call was shed while queued
+}
+
+// NewShedder lets maxConcurrent calls in at once and queues the others.
+func NewShedder(maxConcurrent int, opts ...ShedderOption) *Shedder {
+	config := shedderConfig{target: 5 * time.Millisecond, interval: 100 * time.Millisecond, clock: realClock{}}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &Shedder{maxConcurrent: maxConcurrent, config: config, lastEmpty: config.clock.Now()}
+}
+
+// Overloaded reports whether the queue has been standing for longer than the interval.
+func (s *Shedder) Overloaded() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.overloaded(s.config.clock.Now())
+}
+
+// Acquire takes a place, queueing for one if need be, or fails with ErrShed.  release must be called once the call
+// is done.
+func (s *Shedder) Acquire(ctx context.Context) (release func(), err error) {
+	s.m.Lock()
+	now := s.config.clock.Now()
+	if s.inflight < s.maxConcurrent && len(s.queue) == 0 {
+		s.inflight++
+		s.m.Unlock()
+		return s.release, nil
+	}
+
+	priority := PriorityFrom(ctx)
+	if priority == Sheddable && s.overloaded(now) {
+		s.m.Unlock()
+		return nil, ErrShed
+	}
+	if len(s.queue) == 0 {
+		s.lastEmpty = now // it was empty right up until now
+	}
+	w := &shedWaiter{priority: priority, enqueued: now, ready: make(chan struct{})}
+	s.queue = append(s.queue, w)
+	s.m.Unlock()
+
+	var timeout <-chan time.Time // critical calls wait for as long as their caller does
+	if priority != Critical {
+		timeout = s.config.clock.After(s.config.interval)
+	}
+
+	select {
+	case <-w.ready:
+	case <-timeout:
+		if s.leave(w) {
+			return nil, ErrShed
+		}
+		<-w.ready // it got a place, or was shed, just as it timed out
+	case <-ctx.Done():
+		if s.leave(w) {
+			return nil, ctx.Err()
+		}
+		if <-w.ready; w.err == nil {
+			s.release() // got a place nobody is waiting for any more
+		}
+		return nil, ctx.Err()
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	return s.release, nil
+}
+
+func (s *Shedder) release() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.inflight--
+	s.dispatch(s.config.clock.Now())
+}
+
+// leave takes w out of the queue and reports whether it was still there.
+func (s *Shedder) leave(w *shedWaiter) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for i, queued := range s.queue {
+		if queued == w {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			if len(s.queue) == 0 {
+				s.lastEmpty = s.config.clock.Now()
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch hands free places to queued calls, shedding the ones that queued for too long.  Must be called with the
+// lock held.
+func (s *Shedder) dispatch(now time.Time) {
+	for s.inflight < s.maxConcurrent && len(s.queue) > 0 {
+		overloaded := s.overloaded(now)
+		if overloaded {
+			s.shedStale(now)
+			if len(s.queue) == 0 {
+				break
+			}
+		}
+
+		i := s.next(overloaded && s.config.lifo)
+		w := s.queue[i]
+		s.queue = append(s.queue[:i], s.queue[i+1:]...)
+		s.inflight++
+		close(w.ready)
+	}
+	if len(s.queue) == 0 {
+		s.lastEmpty = now
+	}
+}
+
+// shedStale sheds the calls that have been queued for longer than target, except the critical ones.  Must be called
+// with the lock held.
+func (s *Shedder) shedStale(now time.Time) {
+	kept := s.queue[:0]
+	for _, w := range s.queue {
+		if w.priority != Critical && now.Sub(w.enqueued) > s.config.target {
+			w.err = ErrShed
+			close(w.ready)
+			continue
+		}
+		kept = append(kept, w)
+	}
+	s.queue = kept
+}
+
+// next picks the queued call to serve: the highest priority first, then the oldest or, with lifo, the newest.  The
+// queue is in arrival order.  Must be called with the lock held.
+func (s *Shedder) next(lifo bool) int {
+	best := 0
+	for i, w := range s.queue[1:] {
+		if p := s.queue[best].priority; w.priority > p || (w.priority == p && lifo) {
+			best = i + 1
+		}
+	}
+	return best
+}
+
+// overloaded is true once the queue hasn't been empty for a whole interval.  Must be called with the lock held.
+func (s *Shedder) overloaded(now time.Time) bool {
+	return len(s.queue) > 0 && now.Sub(s.lastEmpty) > s.config.interval
+}
+
+// Shed lets calls into e as s allows, shedding them when it is overloaded.
+func Shed[T any](e Effector[T], s *Shedder) Effector[T] {
+	return func(ctx context.Context) (T, error) {
+		release, err := s.Acquire(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		defer release()
+		return e(ctx)
+	}
+}
@@ -0,0 +1,147 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// queued waits until n calls are queued in s.
+func queued(t *testing.T, s *Shedder, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.m.Lock()
+		got := len(s.queue)
+		s.m.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d calls queued, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// acquire starts an Acquire with priority p and returns a channel with its outcome.
+func acquire(s *Shedder, p Priority) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		release, err := s.Acquire(WithPriority(context.Background(), p))
+		if err == nil {
+			release()
+		}
+		result <- err
+	}()
+	return result
+}
+
+func pending(t *testing.T, result <-chan error) {
+	t.Helper()
+	select {
+	case err := <-result:
+		t.Fatalf("call returned %v, want it still queued", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func returned(t *testing.T, result <-chan error, want error) {
+	t.Helper()
+	select {
+	case err := <-result:
+		if !errors.Is(err, want) {
+			t.Fatalf("got %v, want %v", err, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("call is still queued")
+	}
+}
+
+func TestShedderNeverShedsCritical(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	s := NewShedder(1, WithCoDel(5*time.Millisecond, 50*time.Millisecond), WithShedderClock(clock))
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	critical := acquire(s, Critical)
+	normal := acquire(s, Normal)
+	queued(t, s, 2)
+	clock.Advance(time.Second)
+	returned(t, normal, ErrShed) // queued for longer than the interval
+	pending(t, critical)
+
+	release()
+	returned(t, critical, nil)
+}
+
+func TestShedderTurnsAwaySheddableWhenOverloaded(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	s := NewShedder(1, WithShedderClock(clock))
+	release, _ := s.Acquire(context.Background())
+	defer release()
+
+	sheddable := acquire(s, Sheddable)
+	queued(t, s, 1)
+	pending(t, sheddable) // not overloaded yet, so it may queue
+
+	critical := acquire(s, Critical)
+	queued(t, s, 2)
+	clock.Advance(100 * time.Millisecond)
+	returned(t, sheddable, ErrShed)
+	clock.Advance(10 * time.Millisecond) // the critical call keeps the queue standing for over an interval
+	if !s.Overloaded() {
+		t.Fatal("not overloaded with a queue standing for 110ms")
+	}
+	if _, err := s.Acquire(WithPriority(context.Background(), Sheddable)); !errors.Is(err, ErrShed) {
+		t.Fatalf("got %v, want a sheddable call turned away straight away", err)
+	}
+	pending(t, critical)
+}
+
+func TestShedderShedsStaleCallsWhenOverloaded(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	s := NewShedder(1, WithShedderClock(clock))
+	release, _ := s.Acquire(context.Background())
+
+	critical := acquire(s, Critical)
+	queued(t, s, 1)
+	clock.Advance(80 * time.Millisecond)
+	normal := acquire(s, Normal)
+	queued(t, s, 2)
+	clock.Advance(40 * time.Millisecond) // overloaded, and the normal call has queued for longer than target
+
+	release()
+	returned(t, normal, ErrShed)
+	returned(t, critical, nil)
+}
+
+func TestShedderServesByPriority(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	s := NewShedder(1, WithShedderClock(clock))
+	release, _ := s.Acquire(context.Background())
+
+	served := make(chan Priority, 2)
+	for i, p := range []Priority{Normal, Critical} {
+		p := p
+		go func() {
+			release, err := s.Acquire(WithPriority(context.Background(), p))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			served <- p
+			release()
+		}()
+		queued(t, s, i+1)
+	}
+
+	release()
+	if first := <-served; first != Critical {
+		t.Fatalf("served %v first, want the critical call", first)
+	}
+	<-served
+}
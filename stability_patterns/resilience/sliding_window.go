@@ -0,0 +1,237 @@
+package resilience
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLog allows at most limit calls in any window long stretch of time.  It keeps the time of every call
+// let through in the last window, so memory grows with the limit.
+type SlidingWindowLog struct {
+	limit  int
+	window time.Duration
+	clock  Clock
+
+	m     sync.Mutex
+	calls []time.Time // a ring buffer of the calls in the window, oldest at head
+	head  int
+	count int
+}
+
+func NewSlidingWindowLog(limit uint, window time.Duration, opts ...LimiterOption) *SlidingWindowLog {
+	return &SlidingWindowLog{
+		limit:  int(limit),
+		window: window,
+		clock:  newLimiterConfig(opts).clock,
+		calls:  make([]time.Time, limit),
+	}
+}
+
+func (l *SlidingWindowLog) Allow() bool {
+	_, ok := l.take()
+	return ok
+}
+
+func (l *SlidingWindowLog) Take() error {
+	if delay, ok := l.take(); !ok {
+		return &ThrottledError{Delay: delay}
+	}
+	return nil
+}
+
+func (l *SlidingWindowLog) Wait(ctx context.Context) error {
+	return waitFor(ctx, l.clock, l.take)
+}
+
+// take lets the call through, or says how long until the oldest call in the window drops out of it.
+func (l *SlidingWindowLog) take() (time.Duration, bool) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.limit == 0 {
+		return time.Duration(math.MaxInt64), false
+	}
+	now := l.clock.Now()
+	for l.count > 0 && !l.calls[l.head].After(now.Add(-l.window)) {
+		l.head = (l.head + 1) % l.limit
+		l.count--
+	}
+	if l.count == l.limit {
+		return l.calls[l.head].Add(l.window).Sub(now), false
+	}
+	l.calls[(l.head+l.count)%l.limit] = now
+	l.count++
+	return 0, true
+}
+
+// giveBack forgets the latest call, taken by a MultiLimiter that another tier turned down.
+func (l *SlidingWindowLog) giveBack() {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.count > 0 {
+		l.count--
+	}
+}
+
+func (l *SlidingWindowLog) limiterClock() Clock { return l.clock }
+
+// SlidingWindowCounter approximates a sliding window from the counts of the current and the previous fixed window,
+// assuming the calls of the previous window were spread evenly over it.  Memory stays the same whatever the limit.
+type SlidingWindowCounter struct {
+	limit  float64
+	window time.Duration
+	clock  Clock
+
+	m        sync.Mutex
+	start    time.Time // when the current fixed window began
+	current  float64
+	previous float64
+}
+
+func NewSlidingWindowCounter(limit uint, window time.Duration, opts ...LimiterOption) *SlidingWindowCounter {
+	clock := newLimiterConfig(opts).clock
+	if window <= 0 {
+		window = time.Nanosecond
+	}
+	return &SlidingWindowCounter{limit: float64(limit), window: window, clock: clock, start: clock.Now()}
+}
+
+func (c *SlidingWindowCounter) Allow() bool {
+	_, ok := c.take()
+	return ok
+}
+
+func (c *SlidingWindowCounter) Take() error {
+	if delay, ok := c.take(); !ok {
+		return &ThrottledError{Delay: delay}
+	}
+	return nil
+}
+
+func (c *SlidingWindowCounter) Wait(ctx context.Context) error {
+	return waitFor(ctx, c.clock, c.take)
+}
+
+func (c *SlidingWindowCounter) take() (time.Duration, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	now := c.clock.Now()
+	if windows := now.Sub(c.start) / c.window; windows > 0 {
+		c.previous = c.current
+		if windows > 1 {
+			c.previous = 0 // a whole window went by without a call
+		}
+		c.current = 0
+		c.start = c.start.Add(windows * c.window)
+	}
+
+	elapsed := now.Sub(c.start)
+	weight := 1 - float64(elapsed)/float64(c.window) // how much of the previous window still overlaps ours
+	if c.previous*weight+c.current+1 <= c.limit {
+		c.current++
+		return 0, true
+	}
+
+	return c.delay(elapsed), false
+}
+
+// delay is how long until a call gets through, elapsed into the current window.  Must be called with the lock held.
+func (c *SlidingWindowCounter) delay(elapsed time.Duration) time.Duration {
+	window := float64(c.window)
+	if c.current+1 <= c.limit {
+		// Later in this window, once previous*(1-t/window)+current+1 <= limit.
+		t := time.Duration(math.Ceil((1 - (c.limit-1-c.current)/c.previous) * window))
+		if t < c.window {
+			if t <= elapsed {
+				return time.Nanosecond
+			}
+			return t - elapsed
+		}
+	}
+	if c.limit < 1 {
+		return time.Duration(math.MaxInt64) // never
+	}
+	// In the next window, where the current one is the previous, once current*(1-t/window)+1 <= limit.
+	var t time.Duration
+	if c.current+1 > c.limit {
+		t = time.Duration(math.Ceil((1 - (c.limit-1)/c.current) * window))
+	}
+	return c.window - elapsed + t
+}
+
+// giveBack forgets a call counted in the current window.
+func (c *SlidingWindowCounter) giveBack() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.current > 0 {
+		c.current--
+	}
+}
+
+func (c *SlidingWindowCounter) limiterClock() Clock { return c.clock }
+
+// GCRA is the generic cell rate algorithm.  It lets rate calls per period through, evenly spaced, with room for a
+// burst of burst calls.  All it remembers is the theoretical arrival time of the next call.
+type GCRA struct {
+	interval  time.Duration // the spacing between calls at the sustained rate
+	tolerance time.Duration // how far ahead of the schedule a call may be
+	clock     Clock
+
+	m   sync.Mutex
+	tat time.Time
+}
+
+func NewGCRA(rate uint, per time.Duration, burst uint, opts ...LimiterOption) *GCRA {
+	g := &GCRA{interval: time.Duration(math.MaxInt64), clock: newLimiterConfig(opts).clock}
+	if rate > 0 {
+		g.interval = per / time.Duration(rate)
+		if burst > 1 {
+			g.tolerance = g.interval * time.Duration(burst-1)
+		}
+	}
+	return g
+}
+
+func (g *GCRA) Allow() bool {
+	_, ok := g.take()
+	return ok
+}
+
+func (g *GCRA) Take() error {
+	if delay, ok := g.take(); !ok {
+		return &ThrottledError{Delay: delay}
+	}
+	return nil
+}
+
+func (g *GCRA) Wait(ctx context.Context) error {
+	return waitFor(ctx, g.clock, g.take)
+}
+
+func (g *GCRA) take() (time.Duration, bool) {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	now := g.clock.Now()
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	if ahead := tat.Sub(now); ahead > g.tolerance {
+		return ahead - g.tolerance, false
+	}
+	g.tat = tat.Add(g.interval)
+	return 0, true
+}
+
+// giveBack moves the schedule back by the call that won't be made after all.
+func (g *GCRA) giveBack() {
+	g.m.Lock()
+	defer g.m.Unlock()
+	g.tat = g.tat.Add(-g.interval)
+}
+
+func (g *GCRA) limiterClock() Clock { return g.clock }
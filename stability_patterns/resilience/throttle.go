@@ -0,0 +1,53 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrThrottled = errors.New("too many calls")
+
+type throttleConfig struct {
+	wait bool
+}
+
+type ThrottleOption func(*throttleConfig)
+
+// WithThrottleWait makes callers wait for a token, for as long as their context allows, instead of failing fast.
+func WithThrottleWait() ThrottleOption {
+	return func(c *throttleConfig) {
+		c.wait = true
+	}
+}
+
+// Throttle lets at most max calls through, topping the allowance back up by refill every d.  Calls over the limit
+// fail with an error matching ErrThrottled unless WithThrottleWait is given.
+func Throttle[T any](e Effector[T], max uint, refill uint, d time.Duration, opts ...ThrottleOption) Effector[T] {
+	return ThrottleWith(e, NewTokenBucket(max, refill, d), opts...)
+}
+
+// ThrottleWith throttles e with limiter, which may use any algorithm.
+func ThrottleWith[T any](e Effector[T], limiter Limiter, opts ...ThrottleOption) Effector[T] {
+	var config throttleConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(ctx context.Context) (T, error) {
+		var zero T
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+
+		if config.wait {
+			if err := limiter.Wait(ctx); err != nil {
+				return zero, err
+			}
+		} else if err := limiter.Take(); err != nil {
+			return zero, err
+		}
+
+		return e(ctx)
+	}
+}
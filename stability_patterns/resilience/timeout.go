@@ -0,0 +1,123 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/**
+Breaker and Retry trust the effector to give up when its context is done.  Timeout doesn't: it hands the effector a
+context with a deadline and returns as soon as that deadline passes, whether the effector noticed or not.
+
+Inside a Retry a fixed timeout per attempt doesn't fit well with the caller's own deadline.  Five attempts of a second
+each are no use when the caller only has two seconds left, the last attempts would be cut short by the caller's
+deadline anyway.  So when the context carries a deadline, every attempt gets an equal share of the time that is left
+for the attempts still to come, never more than the fixed timeout.
+*/
+
+var ErrTimeout = errors.New("timed out")
+
+// TimeoutError is returned when a call took longer than it was given.  It matches ErrTimeout with errors.Is.
+type TimeoutError struct {
+	Timeout time.Duration // what the call was given
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%v after %v", ErrTimeout, e.Timeout)
+}
+
+func (e *TimeoutError) Is(target error) bool { return target == ErrTimeout }
+
+type timeoutConfig[T any] struct {
+	late func(response T, err error)
+}
+
+type TimeoutOption[T any] func(*timeoutConfig[T])
+
+// WithLateResult is given the outcome of calls that only returned after they timed out, so it can close whatever
+// they returned.  A call that panicked after timing out is reported with an error.
+func WithLateResult[T any](fn func(response T, err error)) TimeoutOption[T] {
+	return func(c *timeoutConfig[T]) {
+		c.late = fn
+	}
+}
+
+// Timeout gives each call to e at most d, or its share of the remaining deadline when it runs inside a Retry.  A d
+// of zero only applies the share.  A panic in e is raised again in the caller, unless the call had already timed out.
+func Timeout[T any](e Effector[T], d time.Duration, opts ...TimeoutOption[T]) Effector[T] {
+	var config timeoutConfig[T]
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(ctx context.Context) (T, error) {
+		var zero T
+		budget, ok := timeoutBudget(ctx, d)
+		if !ok {
+			return e(ctx) // no timeout and no deadline to share
+		}
+		if budget <= 0 {
+			return zero, &TimeoutError{Timeout: budget}
+		}
+
+		child, cancel := context.WithTimeout(ctx, budget)
+		defer cancel()
+
+		type result struct {
+			response T
+			err      error
+			panicked any
+		}
+		results := make(chan result, 1) // buffered so the goroutine can finish even if nobody is listening any more
+		go func() {
+			defer func() {
+				if p := recover(); p != nil { // caught here, where it would take the whole process down
+					results <- result{err: fmt.Errorf("%w: %v", errPanicked, p), panicked: p}
+				}
+			}()
+			response, err := e(child)
+			results <- result{response: response, err: err}
+		}()
+
+		select {
+		case r := <-results:
+			if r.panicked != nil {
+				panic(r.panicked) // on the caller's goroutine, where it can be recovered
+			}
+			if r.err != nil && ctx.Err() == nil && errors.Is(child.Err(), context.DeadlineExceeded) {
+				return r.response, &TimeoutError{Timeout: budget}
+			}
+			return r.response, r.err
+		case <-child.Done():
+			if config.late != nil {
+				go func() {
+					r := <-results
+					config.late(r.response, r.err)
+				}()
+			}
+			if ctx.Err() != nil {
+				return zero, ctx.Err() // the caller gave up, that is not our timeout
+			}
+			return zero, &TimeoutError{Timeout: budget}
+		}
+	}
+}
+
+// timeoutBudget works out how long the next call may take, or false if there is no limit at all.
+func timeoutBudget(ctx context.Context, d time.Duration) (time.Duration, bool) {
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		return d, d > 0
+	}
+
+	share := time.Until(deadline)
+	if attempt, max, ok := AttemptFrom(ctx); ok && max > attempt {
+		share /= time.Duration(max - attempt + 1)
+	}
+	if d > 0 && d < share {
+		return d, true
+	}
+	return share, true
+}
@@ -0,0 +1,162 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// hang ignores its context and answers after d.
+func hang(d time.Duration) Effector[string] {
+	return func(ctx context.Context) (string, error) {
+		time.Sleep(d)
+		return "late", nil
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	fast := Timeout(func(ctx context.Context) (string, error) { return "ok", nil }, time.Second)
+	if got, err := fast(context.Background()); err != nil || got != "ok" {
+		t.Fatalf("got %q, %v, want ok", got, err)
+	}
+
+	slow := Timeout(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, 10*time.Millisecond)
+	_, err := slow(context.Background())
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || !errors.Is(err, ErrTimeout) || timeout.Timeout != 10*time.Millisecond {
+		t.Fatalf("got %v, want a TimeoutError after 10ms", err)
+	}
+}
+
+func TestTimeoutDoesNotWaitForEffectorIgnoringContext(t *testing.T) {
+	start := time.Now()
+	if _, err := Timeout(hang(time.Second), 10*time.Millisecond)(context.Background()); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want %v", err, ErrTimeout)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Fatalf("took %v, want to return once the timeout passed", took)
+	}
+}
+
+func TestTimeoutCallerGivesUp(t *testing.T) {
+	blocking := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	tests := []struct {
+		name   string
+		e      Effector[string]
+		cancel func() (context.Context, context.CancelFunc)
+		want   error
+	}{
+		{"cancelled, effector notices", blocking, cancelledSoon, context.Canceled},
+		{"cancelled, effector ignores it", hang(time.Second), cancelledSoon, context.Canceled},
+		{"caller's deadline is shorter", blocking, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 10*time.Millisecond)
+		}, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.cancel()
+			defer cancel()
+			_, err := Timeout(tt.e, time.Second)(ctx)
+			if !errors.Is(err, tt.want) || errors.Is(err, ErrTimeout) {
+				t.Fatalf("got %v, want %v and not a timeout of ours", err, tt.want)
+			}
+		})
+	}
+}
+
+func cancelledSoon() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	return ctx, cancel
+}
+
+func TestTimeoutSharesDeadlineBetweenAttempts(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want []time.Duration // what each attempt of four is given out of 400ms
+	}{
+		{"equal shares of what is left", 0, []time.Duration{100, 134, 200, 400}},
+		{"never more than the fixed timeout", 150 * time.Millisecond, []time.Duration{100, 134, 150, 150}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var given []time.Duration
+			e := Timeout(func(ctx context.Context) (string, error) {
+				deadline, _ := ctx.Deadline()
+				given = append(given, time.Until(deadline))
+				return "", errUpstream
+			}, tt.d)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+			defer cancel()
+			Retry(e, 3, 0)(ctx)
+
+			if len(given) != len(tt.want) {
+				t.Fatalf("made %d attempts, want %d", len(given), len(tt.want))
+			}
+			for i, want := range tt.want {
+				want *= time.Millisecond
+				if given[i] > want || given[i] < want-20*time.Millisecond {
+					t.Errorf("attempt %d: given %v, want about %v", i+1, given[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutLateResult(t *testing.T) {
+	late := make(chan string, 1)
+	e := Timeout(hang(50*time.Millisecond), 10*time.Millisecond, WithLateResult(func(response string, err error) {
+		late <- response
+	}))
+	if _, err := e(context.Background()); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want %v", err, ErrTimeout)
+	}
+	select {
+	case got := <-late:
+		if got != "late" {
+			t.Fatalf("late result %q, want late", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the late result was never handed over")
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	e := Timeout(func(ctx context.Context) (string, error) { panic("boom") }, time.Second)
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("recovered %v, want the effector's panic", r)
+		}
+	}()
+	e(context.Background())
+	t.Fatal("the panic was swallowed")
+}
+
+func TestTimeoutLatePanic(t *testing.T) {
+	late := make(chan error, 1)
+	e := Timeout(func(ctx context.Context) (string, error) {
+		time.Sleep(50 * time.Millisecond)
+		panic("boom")
+	}, 10*time.Millisecond, WithLateResult(func(_ string, err error) { late <- err }))
+
+	if _, err := e(context.Background()); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want %v", err, ErrTimeout)
+	}
+	select {
+	case err := <-late:
+		if !errors.Is(err, errPanicked) {
+			t.Fatalf("late error %v, want %v", err, errPanicked)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the late panic was never handed over")
+	}
+}
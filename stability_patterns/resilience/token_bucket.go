@@ -0,0 +1,201 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+/**
+A token bucket holds up to max tokens and gets refill tokens back every d.  Every call takes a token and calls are
+turned away, or made to wait, while the bucket is empty.
+
+Rather than topping the bucket up from a goroutine on a ticker, which needs somebody to own and stop that goroutine,
+the bucket works out how many refills it missed whenever it is used.  There is nothing running in the background, so
+the bucket doesn't care whose context was around when it was first used.
+*/
+
+var ErrLimiterStopped = errors.New("limiter stopped")
+
+type TokenBucket struct {
+	max    int64
+	refill int64
+	d      time.Duration
+	clock  Clock
+
+	m          sync.Mutex
+	tokens     int64 // negative while reservations are waiting for tokens that haven't been refilled yet
+	lastRefill time.Time
+	stopped    chan struct{}
+}
+
+// NewTokenBucket returns a full bucket of max tokens that gets refill tokens back every d.
+func NewTokenBucket(max uint, refill uint, d time.Duration, opts ...LimiterOption) *TokenBucket {
+	clock := newLimiterConfig(opts).clock
+	return &TokenBucket{
+		max:        int64(max),
+		refill:     int64(refill),
+		d:          d,
+		clock:      clock,
+		tokens:     int64(max),
+		lastRefill: clock.Now(),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// Allow takes a token if there is one and reports whether it did.  It never waits.
+func (b *TokenBucket) Allow() bool {
+	return b.Take() == nil
+}
+
+// Take takes a token if there is one, otherwise it returns a *ThrottledError saying when the next one is due.
+func (b *TokenBucket) Take() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.isStopped() {
+		return ErrLimiterStopped
+	}
+	b.refillTokens()
+	if b.tokens <= 0 {
+		return &ThrottledError{Delay: b.delayUntil(1)}
+	}
+	b.tokens--
+	return nil
+}
+
+// Wait takes a token, waiting for the next refill when the bucket is empty, until ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r, err := b.Reserve()
+	if err != nil {
+		return err
+	}
+	if r.Delay() == 0 {
+		return nil
+	}
+
+	select {
+	case <-b.clock.After(r.Delay()):
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	case <-b.stopped:
+		return ErrLimiterStopped
+	}
+}
+
+// Reserve takes a token now, even one that will only be refilled later.  The reservation tells the caller how long to
+// wait before using it.
+func (b *TokenBucket) Reserve() (*Reservation, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.isStopped() {
+		return nil, ErrLimiterStopped
+	}
+	b.refillTokens()
+	if b.refill <= 0 && b.tokens <= 0 {
+		return nil, ErrThrottled // the bucket never gets any tokens back
+	}
+	b.tokens--
+	return &Reservation{delay: b.delayUntilAvailable(), bucket: b}, nil
+}
+
+// Stop turns every later call away with ErrLimiterStopped and releases callers waiting in Wait.
+func (b *TokenBucket) Stop() {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if !b.isStopped() {
+		close(b.stopped)
+	}
+}
+
+// giveBack returns a reserved token that won't be used after all.
+func (b *TokenBucket) giveBack() {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.refillTokens()
+	if b.tokens < b.max {
+		b.tokens++
+	}
+}
+
+func (b *TokenBucket) limiterClock() Clock { return b.clock }
+
+// full reports whether the bucket holds all its tokens, so nothing is lost by throwing it away.
+func (b *TokenBucket) full() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.refillTokens()
+	return b.tokens >= b.max
+}
+
+// delayUntilAvailable is how long until the refills have paid off a negative balance.  Must be called with the lock
+// held and after refillTokens.
+func (b *TokenBucket) delayUntilAvailable() time.Duration {
+	return b.delayUntil(0)
+}
+
+// delayUntil is how long until the bucket holds at least n tokens.  Must be called with the lock held and after
+// refillTokens.
+func (b *TokenBucket) delayUntil(n int64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	if b.refill <= 0 || n > b.max {
+		return time.Duration(math.MaxInt64) // never
+	}
+	refills := (n - b.tokens + b.refill - 1) / b.refill
+	return b.lastRefill.Add(time.Duration(refills) * b.d).Sub(b.clock.Now())
+}
+
+// refillTokens adds the refills that happened since the last one.  Must be called with the lock held.
+func (b *TokenBucket) refillTokens() {
+	now := b.clock.Now()
+	if b.d <= 0 {
+		b.tokens = b.max
+		b.lastRefill = now
+		return
+	}
+	refills := int64(now.Sub(b.lastRefill) / b.d)
+	if refills <= 0 {
+		return
+	}
+	b.lastRefill = b.lastRefill.Add(time.Duration(refills) * b.d)
+	if b.refill > 0 && refills > (b.max-b.tokens)/b.refill {
+		b.tokens = b.max // saves multiplying a huge number of refills
+		return
+	}
+	b.tokens += refills * b.refill
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+func (b *TokenBucket) isStopped() bool {
+	select {
+	case <-b.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reservation is a token taken by Reserve.
+type Reservation struct {
+	delay  time.Duration
+	bucket *TokenBucket
+	once   sync.Once
+}
+
+// Delay is how long the caller has to wait before the token may be used.
+func (r *Reservation) Delay() time.Duration { return r.delay }
+
+// Cancel gives the token back to the bucket when the caller won't use it after all.
+func (r *Reservation) Cancel() {
+	r.once.Do(r.bucket.giveBack)
+}